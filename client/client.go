@@ -36,6 +36,17 @@ type (
 		DelegateID string `json:"delegateId"`
 	}
 
+	HeartbeatResponse struct {
+		Resource HeartbeatData `json:"resource"`
+	}
+
+	HeartbeatData struct {
+		DelegateID string `json:"delegateId"`
+		Status     string `json:"status,omitempty"`
+		// Tasks which have been aborted on the task server and should be stopped by the runner
+		AbortedTaskIDs []string `json:"abortedTaskIds,omitempty"`
+	}
+
 	TaskEventsResponse struct {
 		TaskEvents []*TaskEvent `json:"delegateTaskEvents"`
 	}
@@ -74,7 +85,7 @@ type (
 		ID   string          `json:"id"`
 		Data json.RawMessage `json:"data"`
 		Type string          `json:"type"`
-		Code string          `json:"code"` // OK, FAILED, RETRY_ON_OTHER_DELEGATE
+		Code string          `json:"code"` // OK, FAILED, RETRY_ON_OTHER_DELEGATE, TIMEOUT, ABORTED
 	}
 
 	RunnerTaskResponse struct {
@@ -90,15 +101,20 @@ type (
 	}
 )
 
+// Status reported in the heartbeat response for a delegate which is no longer registered
+const DelegateDeleted = "DELETED"
+
 type (
 	ResponseCode string
 )
 
 const (
-	Unknown ResponseCode = "UNKNOWN"
-	Success ResponseCode = "OK"
-	Failure ResponseCode = "FAILED"
-	Timeout ResponseCode = "TIMEOUT"
+	Unknown              ResponseCode = "UNKNOWN"
+	Success              ResponseCode = "OK"
+	Failure              ResponseCode = "FAILED"
+	Timeout              ResponseCode = "TIMEOUT"
+	Aborted              ResponseCode = "ABORTED"
+	RetryOnOtherDelegate ResponseCode = "RETRY_ON_OTHER_DELEGATE"
 )
 
 // Client is an interface which defines methods on interacting with a task managing system.
@@ -106,8 +122,13 @@ type Client interface {
 	// Register registers the runner with the task server
 	Register(ctx context.Context, r *RegisterRequest) (*RegisterResponse, error)
 
-	// Heartbeat pings the task server to let it know that the runner is still alive
-	Heartbeat(ctx context.Context, r *RegisterRequest) error
+	// Unregister tells the task server that the runner is shutting down, so that it
+	// stops counting on the runner without waiting for its heartbeats to time out.
+	Unregister(ctx context.Context, r *RegisterRequest) error
+
+	// Heartbeat pings the task server to let it know that the runner is still alive.
+	// The response lists the tasks which have been aborted on the task server.
+	Heartbeat(ctx context.Context, r *RegisterRequest) (*HeartbeatResponse, error)
 
 	// GetTaskEvents gets a list of pending tasks that need to be executed for this runner
 	GetTaskEvents(ctx context.Context, delegateID string) (*TaskEventsResponse, error)
@@ -124,3 +145,11 @@ type Client interface {
 	// Register delegate capapcity for a host for CI tasks
 	RegisterCapacity(ctx context.Context, delegateID string, req *DelegateCapacity) error
 }
+
+// Pauser is implemented by clients which hold off calls to a struggling task server,
+// like delegate.HTTPClient while its circuit breaker is open. The poller does not poll
+// while calls to GetTaskEvents are paused.
+type Pauser interface {
+	// Paused returns true while calls to the method are held off
+	Paused(method string) bool
+}
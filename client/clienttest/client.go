@@ -0,0 +1,322 @@
+// Package clienttest provides an in-memory implementation of client.Client
+// for testing task handlers and the poller without a task server.
+package clienttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+)
+
+// Names of the client.Client methods, used to inject errors and latencies
+const (
+	Register         = client.MethodRegister
+	Unregister       = client.MethodUnregister
+	Heartbeat        = client.MethodHeartbeat
+	GetTaskEvents    = client.MethodGetTaskEvents
+	Acquire          = client.MethodAcquire
+	SendStatus       = client.MethodSendStatus
+	SendRunnerStatus = client.MethodSendRunnerStatus
+	RegisterCapacity = client.MethodRegisterCapacity
+)
+
+// DefaultDelegateID is the delegate ID returned by Register unless another one is set
+const DefaultDelegateID = "clienttest-delegate"
+
+// ErrNotFound is returned by Acquire for a task which is not queued. client.IsNotFound
+// recognizes it, like the not found errors of the other clients.
+var ErrNotFound error = &client.StatusError{Code: http.StatusNotFound, Err: errors.New("task not found")}
+
+type (
+	// Status is a status sent for a task through SendStatus
+	Status struct {
+		DelegateID string
+		TaskID     string
+		Response   client.TaskResponse
+	}
+
+	// RunnerStatus is a status sent for a task through SendRunnerStatus
+	RunnerStatus struct {
+		DelegateID string
+		TaskID     string
+		Response   client.RunnerTaskResponse
+	}
+
+	// Capacity is a capacity registered through RegisterCapacity
+	Capacity struct {
+		DelegateID string
+		Capacity   client.DelegateCapacity
+	}
+)
+
+// Client is an in-memory client.Client. Tasks are queued with Enqueue and handed out
+// through GetTaskEvents and Acquire. Every call is recorded so that tests can assert
+// on it. It is safe for concurrent use.
+type Client struct {
+	mu              sync.Mutex
+	delegateID      string
+	pending         []*client.Task
+	aborted         []string
+	registrations   []client.RegisterRequest
+	unregistrations []client.RegisterRequest
+	heartbeats      []client.RegisterRequest
+	capacities      []Capacity
+	statuses        []Status
+	runnerStatuses  []RunnerStatus
+	errs            map[string]error
+	latencies       map[string]time.Duration
+	changed         chan struct{} // closed and replaced whenever a call is recorded
+}
+
+var _ client.Client = (*Client)(nil)
+
+// New returns an empty in-memory client
+func New() *Client {
+	return &Client{
+		delegateID: DefaultDelegateID,
+		errs:       map[string]error{},
+		latencies:  map[string]time.Duration{},
+		changed:    make(chan struct{}),
+	}
+}
+
+// SetDelegateID sets the delegate ID returned by Register
+func (c *Client) SetDelegateID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delegateID = id
+}
+
+// Enqueue queues tasks which are handed out in order by GetTaskEvents until they are acquired
+func (c *Client) Enqueue(tasks ...*client.Task) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, tasks...)
+}
+
+// Abort lists a task as aborted in the response of the next heartbeat
+func (c *Client) Abort(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.aborted = append(c.aborted, taskID)
+}
+
+// SetError makes every call to the method fail with err. A nil error clears it.
+func (c *Client) SetError(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.errs, method)
+		return
+	}
+	c.errs[method] = err
+}
+
+// SetLatency delays every call to the method by d, or until the context of the call is done
+func (c *Client) SetLatency(method string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latencies[method] = d
+}
+
+// Pending returns the tasks which have not been acquired yet
+func (c *Client) Pending() []*client.Task {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*client.Task(nil), c.pending...)
+}
+
+// Registrations returns the requests received by Register
+func (c *Client) Registrations() []client.RegisterRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]client.RegisterRequest(nil), c.registrations...)
+}
+
+// Unregistrations returns the requests received by Unregister
+func (c *Client) Unregistrations() []client.RegisterRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]client.RegisterRequest(nil), c.unregistrations...)
+}
+
+// Heartbeats returns the requests received by Heartbeat
+func (c *Client) Heartbeats() []client.RegisterRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]client.RegisterRequest(nil), c.heartbeats...)
+}
+
+// Capacities returns the capacities received by RegisterCapacity
+func (c *Client) Capacities() []Capacity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Capacity(nil), c.capacities...)
+}
+
+// Statuses returns the statuses received by SendStatus
+func (c *Client) Statuses() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Status(nil), c.statuses...)
+}
+
+// RunnerStatuses returns the statuses received by SendRunnerStatus
+func (c *Client) RunnerStatuses() []RunnerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]RunnerStatus(nil), c.runnerStatuses...)
+}
+
+// WaitForStatuses blocks until at least n statuses have been received through
+// SendStatus and SendRunnerStatus combined, or ctx is done.
+func (c *Client) WaitForStatuses(ctx context.Context, n int) error {
+	for {
+		c.mu.Lock()
+		received := len(c.statuses) + len(c.runnerStatuses)
+		changed := c.changed
+		c.mu.Unlock()
+		if received >= n {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("received %d of %d statuses: %w", received, n, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// Register records the request and returns the delegate ID
+func (c *Client) Register(ctx context.Context, r *client.RegisterRequest) (*client.RegisterResponse, error) {
+	if err := c.call(ctx, Register); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registrations = append(c.registrations, *r)
+	c.notify()
+	return &client.RegisterResponse{Resource: client.RegistrationData{DelegateID: c.delegateID}}, nil
+}
+
+// Unregister records the request
+func (c *Client) Unregister(ctx context.Context, r *client.RegisterRequest) error {
+	if err := c.call(ctx, Unregister); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unregistrations = append(c.unregistrations, *r)
+	c.notify()
+	return nil
+}
+
+// Heartbeat records the request and returns the tasks aborted since the last heartbeat
+func (c *Client) Heartbeat(ctx context.Context, r *client.RegisterRequest) (*client.HeartbeatResponse, error) {
+	if err := c.call(ctx, Heartbeat); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heartbeats = append(c.heartbeats, *r)
+	resp := &client.HeartbeatResponse{Resource: client.HeartbeatData{DelegateID: r.ID, AbortedTaskIDs: c.aborted}}
+	c.aborted = nil
+	c.notify()
+	return resp, nil
+}
+
+// GetTaskEvents returns an event for every task which has not been acquired yet
+func (c *Client) GetTaskEvents(ctx context.Context, delegateID string) (*client.TaskEventsResponse, error) {
+	if err := c.call(ctx, GetTaskEvents); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp := &client.TaskEventsResponse{}
+	for _, t := range c.pending {
+		resp.TaskEvents = append(resp.TaskEvents, &client.TaskEvent{TaskID: t.ID, TaskType: t.Type, Sync: !t.Async})
+	}
+	return resp, nil
+}
+
+// Acquire hands out a queued task. A task can only be acquired once.
+func (c *Client) Acquire(ctx context.Context, delegateID, taskID string) (*client.Task, error) {
+	if err := c.call(ctx, Acquire); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, t := range c.pending {
+		if t.ID == taskID {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			c.notify()
+			task := *t
+			return &task, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// SendStatus records the status of a task
+func (c *Client) SendStatus(ctx context.Context, delegateID, taskID string, r *client.TaskResponse) error {
+	if err := c.call(ctx, SendStatus); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statuses = append(c.statuses, Status{DelegateID: delegateID, TaskID: taskID, Response: *r})
+	c.notify()
+	return nil
+}
+
+// SendRunnerStatus records the status of a task
+func (c *Client) SendRunnerStatus(ctx context.Context, delegateID, taskID string, r *client.RunnerTaskResponse) error {
+	if err := c.call(ctx, SendRunnerStatus); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runnerStatuses = append(c.runnerStatuses, RunnerStatus{DelegateID: delegateID, TaskID: taskID, Response: *r})
+	c.notify()
+	return nil
+}
+
+// RegisterCapacity records the capacity of a delegate
+func (c *Client) RegisterCapacity(ctx context.Context, delegateID string, r *client.DelegateCapacity) error {
+	if err := c.call(ctx, RegisterCapacity); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacities = append(c.capacities, Capacity{DelegateID: delegateID, Capacity: *r})
+	c.notify()
+	return nil
+}
+
+// call applies the latency and error injected for a method
+func (c *Client) call(ctx context.Context, method string) error {
+	c.mu.Lock()
+	latency, err := c.latencies[method], c.errs[method]
+	c.mu.Unlock()
+	if latency > 0 {
+		t := time.NewTimer(latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return err
+}
+
+// notify wakes up the callers waiting for calls to be recorded. It must be called with the lock held.
+func (c *Client) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// StatusCoder is implemented by errors of the task server which carry a status code.
+// Transports which do not speak HTTP map their errors onto the closest HTTP status code,
+// so that callers can tell them apart without knowing about the transport.
+type StatusCoder interface {
+	Status() int
+}
+
+// StatusError is an error of the task server along with its status code
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("task server responded with status %d: %s", e.Code, e.Err)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the status code of the error
+func (e *StatusError) Status() int {
+	return e.Code
+}
+
+// StatusCode returns the status code carried by err, if any
+func StatusCode(err error) (int, bool) {
+	var s StatusCoder
+	if !errors.As(err, &s) {
+		return 0, false
+	}
+	return s.Status(), true
+}
+
+// IsUnauthorized returns true if the task server rejected the token of the runner
+func IsUnauthorized(err error) bool {
+	code, ok := StatusCode(err)
+	return ok && code == http.StatusUnauthorized
+}
+
+// IsNotFound returns true if the task server does not know the delegate or task a call was about
+func IsNotFound(err error) bool {
+	code, ok := StatusCode(err)
+	return ok && code == http.StatusNotFound
+}
+
+// TokenRefresher is implemented by clients which can replace a token rejected by the
+// task server, like delegate.HTTPClient. The poller refreshes the token on IsUnauthorized errors.
+type TokenRefresher interface {
+	// RefreshToken drops the current token, so that the next call is made with a new one
+	RefreshToken()
+}
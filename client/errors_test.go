@@ -0,0 +1,32 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestIsNotFound(t *testing.T) {
+	notFound := &StatusError{Code: http.StatusNotFound, Err: errors.New("no such task")}
+	tests := []struct {
+		err          error
+		notFound     bool
+		unauthorized bool
+	}{
+		{err: notFound, notFound: true},
+		{err: fmt.Errorf("acquire: %w", notFound), notFound: true},
+		{err: &StatusError{Code: http.StatusUnauthorized, Err: errors.New("bad token")}, unauthorized: true},
+		{err: &StatusError{Code: http.StatusInternalServerError, Err: errors.New("oops")}},
+		{err: errors.New("connection refused")},
+		{err: nil},
+	}
+	for _, test := range tests {
+		if got := IsNotFound(test.err); got != test.notFound {
+			t.Errorf("IsNotFound(%v) = %t, want %t", test.err, got, test.notFound)
+		}
+		if got := IsUnauthorized(test.err); got != test.unauthorized {
+			t.Errorf("IsUnauthorized(%v) = %t, want %t", test.err, got, test.unauthorized)
+		}
+	}
+}
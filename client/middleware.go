@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/wings-software/dlite/logger"
+)
+
+// Names of the Client methods, as reported to interceptors
+const (
+	MethodRegister         = "Register"
+	MethodUnregister       = "Unregister"
+	MethodHeartbeat        = "Heartbeat"
+	MethodGetTaskEvents    = "GetTaskEvents"
+	MethodAcquire          = "Acquire"
+	MethodSendStatus       = "SendStatus"
+	MethodSendRunnerStatus = "SendRunnerStatus"
+	MethodRegisterCapacity = "RegisterCapacity"
+)
+
+// Middleware decorates a Client, e.g. to add logging or metrics to every call
+type Middleware func(Client) Client
+
+// Chain wraps c with the given middlewares. The first middleware is the outermost
+// one, so it sees a call first and its result last.
+func Chain(c Client, mws ...Middleware) Client {
+	for i := len(mws) - 1; i >= 0; i-- {
+		c = mws[i](c)
+	}
+	return c
+}
+
+// Call describes a call to a method of a Client. Response, Err and Duration
+// are filled in once the call has returned.
+type Call struct {
+	Method     string
+	DelegateID string      // delegate ID argument, for the methods which take one
+	TaskID     string      // task ID argument, for the methods which take one
+	Request    interface{} // request argument, e.g. *RegisterRequest
+	Response   interface{} // response returned, e.g. *TaskEventsResponse
+	Err        error
+	Duration   time.Duration
+}
+
+// Interceptor is wrapped around every call to a Client. It must call invoke to make the
+// call, and can change the context, inspect the call and its result, or replace the error.
+type Interceptor func(ctx context.Context, call *Call, invoke func(ctx context.Context) error) error
+
+// Intercept returns a middleware which runs every call through the interceptor
+func Intercept(interceptor Interceptor) Middleware {
+	return func(next Client) Client {
+		return &intercepted{next: next, interceptor: interceptor}
+	}
+}
+
+// Logging returns a middleware which logs every call along with its duration.
+// Failed calls are logged as errors, the other ones at debug level.
+func Logging(log logger.Logger) Middleware {
+	return Intercept(func(ctx context.Context, call *Call, invoke func(context.Context) error) error {
+		err := invoke(ctx)
+		if err != nil {
+			log.Errorf("client: %s failed after %s, delegate: %s, task: %s, error: %s", call.Method, call.Duration, call.DelegateID, call.TaskID, err)
+		} else {
+			log.Debugf("client: %s completed in %s, delegate: %s, task: %s", call.Method, call.Duration, call.DelegateID, call.TaskID)
+		}
+		return err
+	})
+}
+
+// Timing returns a middleware which reports the duration and error of every call to observe,
+// e.g. to record them as metrics.
+func Timing(observe func(method string, d time.Duration, err error)) Middleware {
+	return Intercept(func(ctx context.Context, call *Call, invoke func(context.Context) error) error {
+		err := invoke(ctx)
+		observe(call.Method, call.Duration, err)
+		return err
+	})
+}
+
+// intercepted runs the calls to a Client through an interceptor
+type intercepted struct {
+	next        Client
+	interceptor Interceptor
+}
+
+func (c *intercepted) Register(ctx context.Context, r *RegisterRequest) (*RegisterResponse, error) {
+	var resp *RegisterResponse
+	call := &Call{Method: MethodRegister, Request: r}
+	err := c.intercept(ctx, call, func(ctx context.Context) (err error) {
+		resp, err = c.next.Register(ctx, r)
+		call.Response = resp
+		return err
+	})
+	return resp, err
+}
+
+func (c *intercepted) Unregister(ctx context.Context, r *RegisterRequest) error {
+	call := &Call{Method: MethodUnregister, DelegateID: r.ID, Request: r}
+	return c.intercept(ctx, call, func(ctx context.Context) error {
+		return c.next.Unregister(ctx, r)
+	})
+}
+
+func (c *intercepted) Heartbeat(ctx context.Context, r *RegisterRequest) (*HeartbeatResponse, error) {
+	var resp *HeartbeatResponse
+	call := &Call{Method: MethodHeartbeat, DelegateID: r.ID, Request: r}
+	err := c.intercept(ctx, call, func(ctx context.Context) (err error) {
+		resp, err = c.next.Heartbeat(ctx, r)
+		call.Response = resp
+		return err
+	})
+	return resp, err
+}
+
+func (c *intercepted) GetTaskEvents(ctx context.Context, delegateID string) (*TaskEventsResponse, error) {
+	var resp *TaskEventsResponse
+	call := &Call{Method: MethodGetTaskEvents, DelegateID: delegateID}
+	err := c.intercept(ctx, call, func(ctx context.Context) (err error) {
+		resp, err = c.next.GetTaskEvents(ctx, delegateID)
+		call.Response = resp
+		return err
+	})
+	return resp, err
+}
+
+func (c *intercepted) Acquire(ctx context.Context, delegateID, taskID string) (*Task, error) {
+	var resp *Task
+	call := &Call{Method: MethodAcquire, DelegateID: delegateID, TaskID: taskID}
+	err := c.intercept(ctx, call, func(ctx context.Context) (err error) {
+		resp, err = c.next.Acquire(ctx, delegateID, taskID)
+		call.Response = resp
+		return err
+	})
+	return resp, err
+}
+
+func (c *intercepted) SendStatus(ctx context.Context, delegateID, taskID string, r *TaskResponse) error {
+	call := &Call{Method: MethodSendStatus, DelegateID: delegateID, TaskID: taskID, Request: r}
+	return c.intercept(ctx, call, func(ctx context.Context) error {
+		return c.next.SendStatus(ctx, delegateID, taskID, r)
+	})
+}
+
+func (c *intercepted) SendRunnerStatus(ctx context.Context, delegateID, taskID string, r *RunnerTaskResponse) error {
+	call := &Call{Method: MethodSendRunnerStatus, DelegateID: delegateID, TaskID: taskID, Request: r}
+	return c.intercept(ctx, call, func(ctx context.Context) error {
+		return c.next.SendRunnerStatus(ctx, delegateID, taskID, r)
+	})
+}
+
+func (c *intercepted) RegisterCapacity(ctx context.Context, delegateID string, r *DelegateCapacity) error {
+	call := &Call{Method: MethodRegisterCapacity, DelegateID: delegateID, Request: r}
+	return c.intercept(ctx, call, func(ctx context.Context) error {
+		return c.next.RegisterCapacity(ctx, delegateID, r)
+	})
+}
+
+// Paused forwards to the wrapped client, so that middlewares do not hide that it is paused
+func (c *intercepted) Paused(method string) bool {
+	if p, ok := c.next.(Pauser); ok {
+		return p.Paused(method)
+	}
+	return false
+}
+
+// RefreshToken forwards to the wrapped client, so that a rejected token can be replaced through middlewares
+func (c *intercepted) RefreshToken() {
+	if r, ok := c.next.(TokenRefresher); ok {
+		r.RefreshToken()
+	}
+}
+
+// intercept passes the call to the interceptor, recording its result once it has been made
+func (c *intercepted) intercept(ctx context.Context, call *Call, invoke func(ctx context.Context) error) error {
+	return c.interceptor(ctx, call, func(ctx context.Context) error {
+		start := time.Now()
+		err := invoke(ctx)
+		call.Duration = time.Since(start)
+		call.Err = err
+		return err
+	})
+}
@@ -0,0 +1,299 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/client/clienttest"
+	"github.com/wings-software/dlite/logger"
+)
+
+func TestInterceptorSeesCalls(t *testing.T) {
+	register := &client.RegisterRequest{ID: "delegate-1"}
+	taskResponse := &client.TaskResponse{ID: "task-1"}
+	runnerResponse := &client.RunnerTaskResponse{ID: "task-1"}
+	capacity := &client.DelegateCapacity{MaxBuilds: 2}
+	tests := []struct {
+		method     string
+		delegateID string
+		taskID     string
+		request    interface{}
+		do         func(ctx context.Context, c client.Client) (interface{}, error)
+	}{
+		{
+			method:  client.MethodRegister,
+			request: register,
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return c.Register(ctx, register)
+			},
+		},
+		{
+			method:     client.MethodUnregister,
+			delegateID: "delegate-1",
+			request:    register,
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return nil, c.Unregister(ctx, register)
+			},
+		},
+		{
+			method:     client.MethodHeartbeat,
+			delegateID: "delegate-1",
+			request:    register,
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return c.Heartbeat(ctx, register)
+			},
+		},
+		{
+			method:     client.MethodGetTaskEvents,
+			delegateID: "delegate-1",
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return c.GetTaskEvents(ctx, "delegate-1")
+			},
+		},
+		{
+			method:     client.MethodAcquire,
+			delegateID: "delegate-1",
+			taskID:     "task-1",
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return c.Acquire(ctx, "delegate-1", "task-1")
+			},
+		},
+		{
+			method:     client.MethodSendStatus,
+			delegateID: "delegate-1",
+			taskID:     "task-1",
+			request:    taskResponse,
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return nil, c.SendStatus(ctx, "delegate-1", "task-1", taskResponse)
+			},
+		},
+		{
+			method:     client.MethodSendRunnerStatus,
+			delegateID: "delegate-1",
+			taskID:     "task-1",
+			request:    runnerResponse,
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return nil, c.SendRunnerStatus(ctx, "delegate-1", "task-1", runnerResponse)
+			},
+		},
+		{
+			method:     client.MethodRegisterCapacity,
+			delegateID: "delegate-1",
+			request:    capacity,
+			do: func(ctx context.Context, c client.Client) (interface{}, error) {
+				return nil, c.RegisterCapacity(ctx, "delegate-1", capacity)
+			},
+		},
+	}
+	for _, test := range tests {
+		for _, fail := range []bool{false, true} {
+			name := test.method
+			if fail {
+				name += "/failure"
+			}
+			t.Run(name, func(t *testing.T) {
+				const latency = 5 * time.Millisecond
+				wrapped := clienttest.New()
+				wrapped.Enqueue(&client.Task{ID: "task-1"})
+				wrapped.SetLatency(test.method, latency)
+				callErr := errors.New("call failed")
+				if fail {
+					wrapped.SetError(test.method, callErr)
+				}
+				var calls []*client.Call
+				c := client.Chain(wrapped, client.Intercept(func(ctx context.Context, call *client.Call, invoke func(context.Context) error) error {
+					if call.Err != nil || call.Response != nil || call.Duration != 0 {
+						t.Error("call result is filled in before the call is made")
+					}
+					err := invoke(ctx)
+					calls = append(calls, call)
+					return err
+				}))
+				resp, err := test.do(context.Background(), c)
+				if len(calls) != 1 {
+					t.Fatalf("got %d intercepted calls, want 1", len(calls))
+				}
+				call := calls[0]
+				if call.Method != test.method {
+					t.Errorf("got method %s, want %s", call.Method, test.method)
+				}
+				if call.DelegateID != test.delegateID || call.TaskID != test.taskID {
+					t.Errorf("got delegate %q and task %q, want %q and %q", call.DelegateID, call.TaskID, test.delegateID, test.taskID)
+				}
+				if call.Request != test.request {
+					t.Errorf("got request %v, want %v", call.Request, test.request)
+				}
+				if call.Response != resp {
+					t.Errorf("got response %v, want the response returned to the caller %v", call.Response, resp)
+				}
+				if fail && resp != nil && !reflect.ValueOf(resp).IsNil() {
+					t.Errorf("got response %v for a failed call, want none", resp)
+				}
+				if call.Err != err {
+					t.Errorf("got error %v, want the error returned to the caller %v", call.Err, err)
+				}
+				switch {
+				case fail && !errors.Is(err, callErr):
+					t.Errorf("got error %v, want %v", err, callErr)
+				case !fail && err != nil:
+					t.Errorf("got error %v for a successful call", err)
+				}
+				if call.Duration < latency {
+					t.Errorf("got duration %s, want at least %s", call.Duration, latency)
+				}
+			})
+		}
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	named := func(name string) client.Middleware {
+		return client.Intercept(func(ctx context.Context, call *client.Call, invoke func(context.Context) error) error {
+			order = append(order, name+" before")
+			err := invoke(ctx)
+			order = append(order, name+" after")
+			return err
+		})
+	}
+	c := client.Chain(clienttest.New(), named("first"), named("second"), named("third"))
+	if _, err := c.GetTaskEvents(context.Background(), "delegate-1"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"first before", "second before", "third before", "third after", "second after", "first after"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("got order %v, want %v", order, want)
+	}
+}
+
+func TestInterceptorReplacesError(t *testing.T) {
+	replaced := errors.New("replaced")
+	c := client.Chain(clienttest.New(), client.Intercept(func(ctx context.Context, call *client.Call, invoke func(context.Context) error) error {
+		if err := invoke(ctx); err != nil {
+			return replaced
+		}
+		return nil
+	}))
+	if _, err := c.Acquire(context.Background(), "delegate-1", "unknown"); !errors.Is(err, replaced) {
+		t.Errorf("got error %v, want the error of the interceptor", err)
+	}
+}
+
+// recordingLogger records the lines logged at debug and error level
+type recordingLogger struct {
+	logger.Logger
+	debug, errors []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.debug = append(l.debug, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLogging(t *testing.T) {
+	wrapped := clienttest.New()
+	wrapped.Enqueue(&client.Task{ID: "task-1"})
+	log := &recordingLogger{Logger: logger.Discard()}
+	c := client.Chain(wrapped, client.Logging(log))
+	ctx := context.Background()
+	if _, err := c.Acquire(ctx, "delegate-1", "task-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Acquire(ctx, "delegate-1", "task-1"); err == nil {
+		t.Fatal("acquired a task twice")
+	}
+	if len(log.debug) != 1 || !strings.Contains(log.debug[0], "Acquire completed") || !strings.Contains(log.debug[0], "task: task-1") {
+		t.Errorf("got debug lines %q, want one for the successful call", log.debug)
+	}
+	if len(log.errors) != 1 || !strings.Contains(log.errors[0], "Acquire failed") || !strings.Contains(log.errors[0], "task not found") {
+		t.Errorf("got error lines %q, want one for the failed call", log.errors)
+	}
+}
+
+func TestTiming(t *testing.T) {
+	type observation struct {
+		method string
+		d      time.Duration
+		err    error
+	}
+	var observed []observation
+	wrapped := clienttest.New()
+	wrapped.SetLatency(client.MethodHeartbeat, 5*time.Millisecond)
+	heartbeatErr := errors.New("heartbeat failed")
+	wrapped.SetError(client.MethodHeartbeat, heartbeatErr)
+	c := client.Chain(wrapped, client.Timing(func(method string, d time.Duration, err error) {
+		observed = append(observed, observation{method, d, err})
+	}))
+	ctx := context.Background()
+	if _, err := c.GetTaskEvents(ctx, "delegate-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Heartbeat(ctx, &client.RegisterRequest{ID: "delegate-1"}); !errors.Is(err, heartbeatErr) {
+		t.Fatalf("got error %v, want %v", err, heartbeatErr)
+	}
+	if len(observed) != 2 {
+		t.Fatalf("got %d observations, want 2", len(observed))
+	}
+	if o := observed[0]; o.method != client.MethodGetTaskEvents || o.err != nil {
+		t.Errorf("got %s observed with error %v, want %s without error", o.method, o.err, client.MethodGetTaskEvents)
+	}
+	if o := observed[1]; o.method != client.MethodHeartbeat || !errors.Is(o.err, heartbeatErr) || o.d < 5*time.Millisecond {
+		t.Errorf("got %s observed after %s with error %v, want %s after at least 5ms with %v", o.method, o.d, o.err, client.MethodHeartbeat, heartbeatErr)
+	}
+}
+
+// paused is a client which holds off every call
+type paused struct {
+	*clienttest.Client
+}
+
+func (paused) Paused(method string) bool { return true }
+
+func TestChainForwardsPaused(t *testing.T) {
+	noop := client.Intercept(func(ctx context.Context, call *client.Call, invoke func(context.Context) error) error {
+		return invoke(ctx)
+	})
+	c := client.Chain(paused{clienttest.New()}, noop, noop)
+	p, ok := c.(client.Pauser)
+	if !ok {
+		t.Fatal("chained client does not implement client.Pauser")
+	}
+	if !p.Paused(client.MethodGetTaskEvents) {
+		t.Error("chained client is not paused, want the wrapped client to be asked")
+	}
+
+	p = client.Chain(clienttest.New(), noop).(client.Pauser)
+	if p.Paused(client.MethodGetTaskEvents) {
+		t.Error("chained client is paused, want a client which cannot pause to never be")
+	}
+}
+
+// refreshing is a client which counts the refreshes of its token
+type refreshing struct {
+	*clienttest.Client
+	refreshed int
+}
+
+func (r *refreshing) RefreshToken() { r.refreshed++ }
+
+func TestChainForwardsRefreshToken(t *testing.T) {
+	r := &refreshing{Client: clienttest.New()}
+	c := client.Chain(r, client.Timing(func(string, time.Duration, error) {}))
+	refresher, ok := c.(client.TokenRefresher)
+	if !ok {
+		t.Fatal("chained client does not implement client.TokenRefresher")
+	}
+	refresher.RefreshToken()
+	if r.refreshed != 1 {
+		t.Errorf("got %d refreshes of the wrapped client, want 1", r.refreshed)
+	}
+}
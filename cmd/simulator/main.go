@@ -0,0 +1,37 @@
+// Command simulator runs a local stand-in for the Harness manager which delegates can
+// register with and poll for tasks. Tasks are enqueued through the admin API:
+//
+//	curl -X POST localhost:3000/admin/tasks -d '{"type": "CI_DOCKER_INITIALIZE_TASK", "runnerResponse": true, "data": {}}'
+package main
+
+import (
+	"flag"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wings-software/dlite/simulator"
+)
+
+func main() {
+	addr := flag.String("addr", ":3000", "address to listen on")
+	doubleAcquire := flag.Bool("double-acquire", false, "allow a delegate to acquire the same task twice")
+	debug := flag.Bool("debug", false, "enable debug logging")
+	flag.Parse()
+
+	if *debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	s := simulator.New()
+	s.AllowDoubleAcquire = *doubleAcquire
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logrus.Infof("simulator listening on %s", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		logrus.WithError(err).Fatalln("simulator stopped")
+	}
+}
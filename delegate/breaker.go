@@ -0,0 +1,169 @@
+package delegate
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// Number of consecutive failures of an endpoint which open its circuit
+	breakerThreshold = 5
+	// Time a circuit stays open before a probe request is let through
+	breakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for calls which are not sent to the manager because
+// the circuit of the endpoint is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open, manager is not being called")
+
+// BreakerState is the state of the circuit of an endpoint
+type BreakerState int
+
+const (
+	// BreakerClosed lets all the requests through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails all the requests fast
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe request through to check if the manager has recovered
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker tracks the failures of the manager per endpoint. After Threshold consecutive
+// failures the circuit of the endpoint opens and calls fail fast with ErrCircuitOpen. Once the
+// cooldown is over, a single probe request is let through: the circuit closes again if it
+// succeeds and opens for another cooldown if it fails.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown, circuits: map[string]*circuit{}}
+}
+
+// Allow returns ErrCircuitOpen if a request to the endpoint should not be sent.
+// Once the cooldown is over, it lets a single probe request through.
+func (b *CircuitBreaker) Allow(endpoint string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(endpoint)
+	switch c.state {
+	case BreakerOpen:
+		if time.Since(c.openedAt) < b.Cooldown {
+			return ErrCircuitOpen
+		}
+		c.state = BreakerHalfOpen
+		return nil
+	case BreakerHalfOpen:
+		// a probe request is already in flight
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+// Record records the result of a request to the endpoint
+func (b *CircuitBreaker) Record(endpoint string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(endpoint)
+	if success {
+		c.state = BreakerClosed
+		c.failures = 0
+		return
+	}
+	c.failures++
+	if c.state == BreakerHalfOpen || c.failures >= b.Threshold {
+		c.state = BreakerOpen
+		c.openedAt = time.Now()
+	}
+}
+
+// cancel gives up on a request to the endpoint which did not get a result. If it was
+// the probe request, the circuit goes back to open so that the next call probes again.
+func (b *CircuitBreaker) cancel(endpoint string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.circuit(endpoint); c.state == BreakerHalfOpen {
+		c.state = BreakerOpen
+	}
+}
+
+// remaining returns the time left until the circuit of the endpoint lets a probe request
+// through. It is zero unless the circuit is open.
+func (b *CircuitBreaker) remaining(endpoint string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(endpoint)
+	if c.state != BreakerOpen {
+		return 0
+	}
+	if d := b.Cooldown - time.Since(c.openedAt); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// State returns the state of the circuit of the endpoint. An open circuit whose cooldown
+// is over is reported as half-open, since the next request is let through as the probe.
+func (b *CircuitBreaker) State(endpoint string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state(b.circuit(endpoint))
+}
+
+// States returns the state of the circuits of all the endpoints which have been called
+func (b *CircuitBreaker) States() map[string]BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := map[string]BreakerState{}
+	for endpoint, c := range b.circuits {
+		states[endpoint] = b.state(c)
+	}
+	return states
+}
+
+// state returns the state of a circuit, taking the cooldown into account.
+// It must be called with the lock held.
+func (b *CircuitBreaker) state(c *circuit) BreakerState {
+	if c.state == BreakerOpen && time.Since(c.openedAt) >= b.Cooldown {
+		return BreakerHalfOpen
+	}
+	return c.state
+}
+
+// circuit returns the circuit of an endpoint. It must be called with the lock held.
+func (b *CircuitBreaker) circuit(endpoint string) *circuit {
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+	c, ok := b.circuits[endpoint]
+	if !ok {
+		c = &circuit{}
+		b.circuits[endpoint] = c
+	}
+	return c
+}
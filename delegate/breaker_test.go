@@ -0,0 +1,29 @@
+package delegate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerHalfOpensAfterCooldown(t *testing.T) {
+	b := NewCircuitBreaker(2, 20*time.Millisecond)
+	b.Record("endpoint", false)
+	b.Record("endpoint", false)
+	if state := b.State("endpoint"); state != BreakerOpen {
+		t.Fatalf("got circuit %s, want it open", state)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if state := b.State("endpoint"); state != BreakerHalfOpen {
+		t.Fatalf("got circuit %s after the cooldown, want it half-open", state)
+	}
+	if err := b.Allow("endpoint"); err != nil {
+		t.Fatalf("probe request was not let through: %s", err)
+	}
+	if err := b.Allow("endpoint"); err != ErrCircuitOpen {
+		t.Errorf("got error %v while the probe is in flight, want %v", err, ErrCircuitOpen)
+	}
+	b.Record("endpoint", true)
+	if state := b.State("endpoint"); state != BreakerClosed {
+		t.Errorf("got circuit %s after the probe succeeded, want it closed", state)
+	}
+}
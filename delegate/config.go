@@ -2,6 +2,7 @@ package delegate
 
 import (
 	"github.com/kelseyhightower/envconfig"
+	"k8s.io/utils/strings/slices"
 )
 
 // Sample config
@@ -14,9 +15,27 @@ type Config struct {
 		AccountSecret   string `envconfig:"DRONE_DELEGATE_ACCOUNT_SECRET"`
 		ManagerEndpoint string `envconfig:"DRONE_DELEGATE_MANAGER_ENDPOINT"`
 		Name            string `envconfig:"DRONE_DELEGATE_NAME"`
+		// Manager endpoints to fail over to, in order of preference
+		FailoverEndpoints []string `envconfig:"DRONE_DELEGATE_MANAGER_FAILOVER_ENDPOINTS"`
 	}
 }
 
+// ManagerEndpoints returns the manager endpoint followed by the failover endpoints
+func (c *Config) ManagerEndpoints() []string {
+	return managerEndpoints(c.Delegate.ManagerEndpoint, c.Delegate.FailoverEndpoints)
+}
+
+// managerEndpoints returns endpoint followed by the failover endpoints, without blanks or duplicates
+func managerEndpoints(endpoint string, failover []string) []string {
+	endpoints := []string{endpoint}
+	for _, e := range failover {
+		if e != "" && !slices.Contains(endpoints, e) {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
 func FromEnviron() (Config, error) {
 	var config Config
 	err := envconfig.Process("", &config)
@@ -0,0 +1,106 @@
+package delegate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// retryableStatusCodes are the statuses of responses which are expected to go away
+// when the request is sent again later
+var retryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Error is returned by HTTPClient when the manager responds with an error status.
+// It implements client.StatusCoder.
+type Error struct {
+	// StatusCode is the status of the response
+	StatusCode int
+	// Endpoint names the call, e.g. client.MethodAcquire
+	Endpoint string
+	// Method and URL of the request
+	Method string
+	URL    string
+	// Body is the error sent by the manager. It is empty if the response body is not a manager error.
+	Body ErrorBody
+	// Raw is the response body
+	Raw []byte
+	// Retryable is true if the error is expected to go away when the request is sent again later
+	Retryable bool
+}
+
+// ErrorBody is the body of an error response of the manager
+type ErrorBody struct {
+	Status           string            `json:"status,omitempty"`
+	Code             string            `json:"code,omitempty"`
+	Message          string            `json:"message,omitempty"`
+	ResponseMessages []ResponseMessage `json:"responseMessages,omitempty"`
+	// ErrorMessage is set by services which respond with httphelper.WriteJSON
+	ErrorMessage string `json:"error_msg,omitempty"`
+}
+
+// ResponseMessage is a message of a manager response
+type ResponseMessage struct {
+	Code    string `json:"code,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// newError returns the error for a response with an error status
+func newError(endpoint, method, url string, status int, body []byte) *Error {
+	err := &Error{
+		StatusCode: status,
+		Endpoint:   endpoint,
+		Method:     method,
+		URL:        url,
+		Raw:        body,
+	}
+	// fields of unexpected types are skipped, the rest of the body is still parsed
+	_ = json.Unmarshal(body, &err.Body)
+	for _, code := range retryableStatusCodes {
+		if status == code {
+			err.Retryable = true
+		}
+	}
+	return err
+}
+
+// Message returns the error message of the manager, or the response body if there is none
+func (e *Error) Message() string {
+	switch {
+	case e.Body.Message != "":
+		return e.Body.Message
+	case len(e.Body.ResponseMessages) != 0 && e.Body.ResponseMessages[0].Message != "":
+		return e.Body.ResponseMessages[0].Message
+	case e.Body.ErrorMessage != "":
+		return e.Body.ErrorMessage
+	case len(e.Raw) != 0:
+		return string(e.Raw)
+	default:
+		return http.StatusText(e.StatusCode)
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed with status %d: %s", e.Endpoint, e.StatusCode, e.Message())
+}
+
+// Status returns the status code of the response, so that client.IsNotFound and
+// client.IsUnauthorized recognize the error.
+func (e *Error) Status() int {
+	return e.StatusCode
+}
+
+// IsRetryable returns true if the manager responded with an error which is expected to go away
+// when the request is sent again later
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Retryable
+}
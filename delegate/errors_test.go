@@ -0,0 +1,149 @@
+package delegate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wings-software/dlite/client"
+)
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+		wantCode    string
+		retryable   bool
+	}{
+		{
+			name:        "manager error",
+			status:      http.StatusBadRequest,
+			body:        `{"status":"ERROR","code":"INVALID_REQUEST","message":"task is invalid"}`,
+			wantMessage: "task is invalid",
+			wantCode:    "INVALID_REQUEST",
+		},
+		{
+			name:        "response messages",
+			status:      http.StatusNotFound,
+			body:        `{"status":"ERROR","responseMessages":[{"code":"RESOURCE_NOT_FOUND","level":"ERROR","message":"no such task"}]}`,
+			wantMessage: "no such task",
+		},
+		{
+			name:        "error message",
+			status:      http.StatusForbidden,
+			body:        `{"error_msg":"account is not allowed"}`,
+			wantMessage: "account is not allowed",
+		},
+		{
+			name:        "field of an unexpected type",
+			status:      http.StatusInternalServerError,
+			body:        `{"code":42,"message":"something broke"}`,
+			wantMessage: "something broke",
+			retryable:   true,
+		},
+		{
+			name:        "not json",
+			status:      http.StatusBadGateway,
+			body:        "<html>upstream is down</html>",
+			wantMessage: "<html>upstream is down</html>",
+			retryable:   true,
+		},
+		{
+			name:        "empty body",
+			status:      http.StatusServiceUnavailable,
+			wantMessage: http.StatusText(http.StatusServiceUnavailable),
+			retryable:   true,
+		},
+		{
+			name:        "too many requests",
+			status:      http.StatusTooManyRequests,
+			body:        `{"message":"slow down"}`,
+			wantMessage: "slow down",
+			retryable:   true,
+		},
+		{
+			name:        "gateway timeout",
+			status:      http.StatusGatewayTimeout,
+			wantMessage: http.StatusText(http.StatusGatewayTimeout),
+			retryable:   true,
+		},
+		{
+			name:        "unauthorized",
+			status:      http.StatusUnauthorized,
+			body:        `{"message":"token expired"}`,
+			wantMessage: "token expired",
+		},
+		{
+			name:        "not implemented",
+			status:      http.StatusNotImplemented,
+			wantMessage: http.StatusText(http.StatusNotImplemented),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, test.body)
+			}))
+			defer server.Close()
+			c := NewFromToken(server.URL, "account", "token", false, "")
+
+			_, err := c.Acquire(context.Background(), "delegate-1", "task-1")
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("got error %v, want an *Error", err)
+			}
+			if e.StatusCode != test.status || e.Status() != test.status {
+				t.Errorf("got status %d, want %d", e.StatusCode, test.status)
+			}
+			if code, _ := client.StatusCode(err); code != test.status {
+				t.Errorf("got status code %d through client.StatusCode, want %d", code, test.status)
+			}
+			if e.Endpoint != client.MethodAcquire || e.Method != http.MethodPut || !strings.HasPrefix(e.URL, server.URL) {
+				t.Errorf("got %s %s for endpoint %s, want a %s to the server for %s", e.Method, e.URL, e.Endpoint, http.MethodPut, client.MethodAcquire)
+			}
+			if string(e.Raw) != test.body {
+				t.Errorf("got raw body %q, want %q", e.Raw, test.body)
+			}
+			if got := e.Message(); got != test.wantMessage {
+				t.Errorf("got message %q, want %q", got, test.wantMessage)
+			}
+			if e.Body.Code != test.wantCode {
+				t.Errorf("got code %q, want %q", e.Body.Code, test.wantCode)
+			}
+			if want := fmt.Sprintf("%s failed with status %d: %s", client.MethodAcquire, test.status, test.wantMessage); !strings.Contains(err.Error(), want) {
+				t.Errorf("got error %q, want it to contain %q", err, want)
+			}
+			if got := IsRetryable(err); got != test.retryable {
+				t.Errorf("got retryable %t, want %t", got, test.retryable)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	retryable := &Error{StatusCode: http.StatusServiceUnavailable, Retryable: true}
+	for _, test := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "retryable", err: retryable, want: true},
+		{name: "wrapped", err: fmt.Errorf("could not acquire task: %w", retryable), want: true},
+		{name: "not retryable", err: &Error{StatusCode: http.StatusBadRequest}},
+		{name: "other error", err: errors.New("connection refused")},
+		{name: "no error"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsRetryable(test.err); got != test.want {
+				t.Errorf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package delegate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/wings-software/dlite/client"
+)
+
+const healthEndpoint = "/api/health"
+
+var (
+	healthCheckInterval = 30 * time.Second
+	healthCheckTimeout  = 10 * time.Second
+	// Number of consecutive failures after which requests fail over away from an endpoint
+	failoverThreshold = 3
+	// Time for which the endpoint which acquired a task is remembered, for tasks without a timeout
+	pinTTL = 24 * time.Hour
+	// Time on top of the timeout of a task for which the endpoint which acquired it is remembered,
+	// enough for its status to be sent and retried
+	pinMargin = 15 * time.Minute
+)
+
+// failover tracks the health of the manager endpoints, in order of preference, and
+// which of them requests are sent to. It also remembers which endpoint acquired a task,
+// so that the status of the task gets reported to the same manager.
+type failover struct {
+	mu       sync.Mutex
+	urls     []string
+	down     []bool
+	failures []int // consecutive failures of every endpoint
+	active   int
+	watching bool          // whether the health of the endpoints is being checked until the preferred one is back
+	pins     *cache.Cache  // task ID to the endpoint which served its acquire
+	closed   chan struct{} // closed once the health of the endpoints is not to be checked anymore
+}
+
+func newFailover(urls []string) *failover {
+	return &failover{
+		urls:     urls,
+		down:     make([]bool, len(urls)),
+		failures: make([]int, len(urls)),
+		pins:     cache.New(pinTTL, pinTTL/24),
+		closed:   make(chan struct{}),
+	}
+}
+
+// close stops the health checks of the endpoints
+func (f *failover) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.closed:
+	default:
+		close(f.closed)
+	}
+}
+
+// current returns the endpoint requests are sent to
+func (f *failover) current() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.urls[f.active]
+}
+
+// next returns the most preferred endpoint which is neither down nor in tried, falling back
+// to any endpoint not in tried, for a request to be sent to after it failed. It returns false
+// if there is no endpoint left to try. The endpoint requests are sent to does not change.
+func (f *failover) next(tried map[string]bool) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	candidate := -1
+	for i, u := range f.urls {
+		if tried[u] {
+			continue
+		}
+		if !f.down[i] {
+			candidate = i
+			break
+		}
+		if candidate < 0 {
+			candidate = i
+		}
+	}
+	if candidate < 0 {
+		return "", false
+	}
+	return f.urls[candidate], true
+}
+
+// fail records a failed request to an endpoint. After failoverThreshold consecutive
+// failures the endpoint is marked as down and requests move away from it.
+func (f *failover) fail(url string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.urls {
+		if u == url {
+			f.failures[i]++
+			if f.failures[i] >= failoverThreshold {
+				f.moveAway(i)
+			}
+		}
+	}
+}
+
+// up marks an endpoint as healthy. Requests fail back to it if it is preferred over the current one.
+func (f *failover) up(url string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.urls {
+		if u != url {
+			continue
+		}
+		f.down[i] = false
+		f.failures[i] = 0
+		if i < f.active {
+			f.active = i
+		}
+	}
+}
+
+// markDown marks an endpoint as unhealthy and moves away from it if it is the current one
+func (f *failover) markDown(url string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, u := range f.urls {
+		if u == url {
+			f.moveAway(i)
+		}
+	}
+}
+
+// moveAway marks an endpoint as down and, if it is the current one, switches to the most
+// preferred endpoint which is not down. It must be called with the lock held.
+func (f *failover) moveAway(i int) {
+	f.down[i] = true
+	if f.active != i {
+		return
+	}
+	for j := range f.urls {
+		if !f.down[j] {
+			f.active = j
+			return
+		}
+	}
+}
+
+// watch returns true if the caller should start checking the health of the endpoints,
+// because requests have failed over away from the preferred endpoint and nobody is
+// checking yet.
+func (f *failover) watch() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.closed:
+		return false
+	default:
+	}
+	if f.watching || len(f.urls) < 2 || (f.active == 0 && !f.down[0]) {
+		return false
+	}
+	f.watching = true
+	return true
+}
+
+// unwatch returns true if the caller can stop checking the health of the endpoints
+// because requests have failed back to the preferred endpoint.
+func (f *failover) unwatch() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active != 0 || f.down[0] {
+		return false
+	}
+	f.watching = false
+	return true
+}
+
+// pin remembers the endpoint which acquired a task, until its status has been sent
+// or the timeout of the task is well over
+func (f *failover) pin(taskID, url string, timeout time.Duration) {
+	ttl := pinTTL
+	if timeout > 0 {
+		ttl = timeout + pinMargin
+	}
+	f.pins.Set(taskID, url, ttl)
+}
+
+func (f *failover) pinned(taskID string) (string, bool) {
+	url, ok := f.pins.Get(taskID)
+	if !ok {
+		return "", false
+	}
+	return url.(string), true
+}
+
+func (f *failover) unpin(taskID string) {
+	f.pins.Delete(taskID)
+}
+
+// failsOver returns true if a request which failed with err, or with a 5xx response if
+// err is nil, can be sent to another endpoint. Requests which are not idempotent, like
+// acquiring a task, only fail over if they could not have reached the manager.
+func failsOver(endpoint string, err error) bool {
+	if endpoint != client.MethodAcquire {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+type serverKey struct{}
+
+// withServer pins the requests made with the returned context to a manager endpoint
+func withServer(ctx context.Context, url string) context.Context {
+	return context.WithValue(ctx, serverKey{}, url)
+}
+
+// serverFrom returns the manager endpoint the context is pinned to, if any
+func serverFrom(ctx context.Context) (string, bool) {
+	url, ok := ctx.Value(serverKey{}).(string)
+	return url, ok
+}
+
+// StartHealthChecks checks the health of every manager endpoint at the given interval until
+// ctx is done or the client is closed. Requests fail back to a preferred endpoint as soon as
+// it is healthy again. Without it, the health of the endpoints is only checked while requests
+// are failed over.
+func (p *HTTPClient) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = healthCheckInterval
+	}
+	ctx, cancel := p.untilClosed(ctx)
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+// Close stops checking the health of the manager endpoints
+func (p *HTTPClient) Close() {
+	p.servers().close()
+}
+
+// watchHealth checks the health of the manager endpoints while requests are failed over
+// away from the preferred endpoint, so that they fail back to it once it has recovered.
+// It stops once the client is closed.
+func (p *HTTPClient) watchHealth() {
+	servers := p.servers()
+	if !servers.watch() {
+		return
+	}
+	interval := healthCheckInterval
+	ctx, cancel := p.untilClosed(context.Background())
+	go func() {
+		defer cancel()
+		t := time.NewTimer(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			p.checkHealth(ctx)
+			if servers.unwatch() {
+				return
+			}
+			t.Reset(interval)
+		}
+	}()
+}
+
+// untilClosed returns a copy of ctx which is cancelled once the client is closed
+func (p *HTTPClient) untilClosed(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		select {
+		case <-ctx.Done():
+		case <-p.servers().closed:
+		}
+	}()
+	return ctx, cancel
+}
+
+// checkHealth updates the health of every manager endpoint. Any response below 500 counts as healthy.
+func (p *HTTPClient) checkHealth(ctx context.Context) {
+	servers := p.servers()
+	for _, url := range servers.urls {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		req, err := http.NewRequestWithContext(checkCtx, "GET", url+healthEndpoint, http.NoBody)
+		if err != nil {
+			cancel()
+			continue
+		}
+		res, err := p.Client.Do(req)
+		cancel()
+		if err == nil {
+			res.Body.Close()
+		}
+		if err != nil || res.StatusCode >= 500 {
+			p.logger().Warnf("manager endpoint %s is unhealthy", url)
+			servers.markDown(url)
+			continue
+		}
+		servers.up(url)
+	}
+}
+
+// servers returns the manager endpoints, in order of preference. They default to Endpoint.
+func (p *HTTPClient) servers() *failover {
+	p.failoverOnce.Do(func() {
+		urls := p.endpoints
+		if len(urls) == 0 {
+			urls = []string{p.Endpoint}
+		}
+		p.failover = newFailover(urls)
+	})
+	return p.failover
+}
@@ -0,0 +1,172 @@
+package delegate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+)
+
+// manager is a test endpoint of the manager which fails while down is set
+type manager struct {
+	*httptest.Server
+	down   int32
+	calls  int32
+	checks int32 // health checks
+}
+
+func newManager(t *testing.T) *manager {
+	m := &manager{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != healthEndpoint {
+			atomic.AddInt32(&m.calls, 1)
+		} else {
+			atomic.AddInt32(&m.checks, 1)
+		}
+		if atomic.LoadInt32(&m.down) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("{}")) //nolint:errcheck
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *manager) setDown(down bool) {
+	var v int32
+	if down {
+		v = 1
+	}
+	atomic.StoreInt32(&m.down, v)
+}
+
+// newFailoverClient returns a client of the managers, in order of preference, which does not retry
+func newFailoverClient(managers ...*manager) *HTTPClient {
+	var failover []string
+	for _, m := range managers[1:] {
+		failover = append(failover, m.URL)
+	}
+	c := NewFromToken(managers[0].URL, "account", "token", false, "", failover...)
+	c.Breaker = nil
+	return c
+}
+
+func TestFailoverAfterConsecutiveFailures(t *testing.T) {
+	primary, secondary := newManager(t), newManager(t)
+	c := newFailoverClient(primary, secondary)
+	primary.setDown(true)
+
+	for i := 1; i <= failoverThreshold; i++ {
+		if _, err := c.Heartbeat(context.Background(), &client.RegisterRequest{}); err != nil {
+			t.Fatalf("heartbeat %d was not sent to the secondary endpoint: %s", i, err)
+		}
+		want := primary.URL
+		if i == failoverThreshold {
+			want = secondary.URL
+		}
+		if got := c.servers().current(); got != want {
+			t.Fatalf("got endpoint %s after %d failures, want %s", got, i, want)
+		}
+	}
+	if got := atomic.LoadInt32(&primary.calls); got != int32(failoverThreshold) {
+		t.Errorf("got %d calls to the primary endpoint, want %d", got, failoverThreshold)
+	}
+}
+
+func TestFailBackOnceHealthy(t *testing.T) {
+	old := healthCheckInterval
+	healthCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { healthCheckInterval = old })
+	primary, secondary := newManager(t), newManager(t)
+	c := newFailoverClient(primary, secondary)
+	primary.setDown(true)
+	for i := 0; i < failoverThreshold; i++ {
+		c.Heartbeat(context.Background(), &client.RegisterRequest{}) //nolint:errcheck
+	}
+	if got := c.servers().current(); got != secondary.URL {
+		t.Fatalf("got endpoint %s, want the client to fail over to %s", got, secondary.URL)
+	}
+
+	primary.setDown(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for c.servers().current() != primary.URL {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not fail back to the primary endpoint")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCloseStopsHealthChecks(t *testing.T) {
+	old := healthCheckInterval
+	healthCheckInterval = 5 * time.Millisecond
+	t.Cleanup(func() { healthCheckInterval = old })
+	primary, secondary := newManager(t), newManager(t)
+	c := newFailoverClient(primary, secondary)
+	primary.setDown(true)
+	for i := 0; i < failoverThreshold; i++ {
+		c.Heartbeat(context.Background(), &client.RegisterRequest{}) //nolint:errcheck
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&primary.checks) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the health of the endpoints is not checked while requests are failed over")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	c.Close()
+	time.Sleep(20 * time.Millisecond) // let a check which was running when the client was closed complete
+	checks := atomic.LoadInt32(&primary.checks)
+	primary.setDown(false)
+	time.Sleep(10 * healthCheckInterval)
+	if got := atomic.LoadInt32(&primary.checks); got != checks {
+		t.Errorf("got %d health checks after the client was closed, want none", got-checks)
+	}
+	if got := c.servers().current(); got != secondary.URL {
+		t.Errorf("got endpoint %s, want the closed client to stay on %s", got, secondary.URL)
+	}
+}
+
+func TestFailoverEndpoints(t *testing.T) {
+	c := New("https://a", "account", "secret", false, "", "https://b", "", "https://a", "https://c", "https://b")
+	if got, want := c.servers().urls, []string{"https://a", "https://b", "https://c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got endpoints %v, want %v", got, want)
+	}
+	if got, want := NewFromToken("https://a", "account", "token", false, "").servers().urls, []string{"https://a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got endpoints %v without failover endpoints, want %v", got, want)
+	}
+}
+
+func TestAcquireDoesNotFailOverOnServerError(t *testing.T) {
+	primary, secondary := newManager(t), newManager(t)
+	c := newFailoverClient(primary, secondary)
+	primary.setDown(true)
+	if _, err := c.Acquire(context.Background(), "delegate", "task"); err == nil {
+		t.Fatal("acquire succeeded, want the error of the primary endpoint")
+	}
+	if got := atomic.LoadInt32(&secondary.calls); got != 0 {
+		t.Errorf("got %d calls to the secondary endpoint, want the acquire not to be sent twice", got)
+	}
+}
+
+func TestPinsExpire(t *testing.T) {
+	old := pinMargin
+	pinMargin = 0
+	t.Cleanup(func() { pinMargin = old })
+	f := newFailover([]string{"a", "b"})
+	f.pin("task", "b", 10*time.Millisecond)
+	if url, ok := f.pinned("task"); !ok || url != "b" {
+		t.Fatalf("got pin %q, want the task pinned to b", url)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := f.pinned("task"); ok {
+		t.Error("task is still pinned after its timeout")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
@@ -23,6 +24,7 @@ import (
 
 const (
 	registerEndpoint         = "/api/agent/delegates/register?accountId=%s"
+	unregisterEndpoint       = "/api/agent/delegates/unregister?accountId=%s"
 	heartbeatEndpoint        = "/api/agent/delegates/heartbeat-with-polling?accountId=%s"
 	taskPollEndpoint         = "/api/agent/delegates/%s/task-events?accountId=%s"
 	taskAcquireEndpoint      = "/api/agent/v2/delegates/%s/tasks/%s/acquire?accountId=%s&delegateInstanceId=%s"
@@ -32,11 +34,8 @@ const (
 	delegateCapacityEndpoint = "/api/agent/delegates/register-delegate-capacity/%s?accountId=%s"
 )
 
-var (
-	registerTimeout      = 30 * time.Second
-	taskEventsTimeout    = 60 * time.Second
-	sendStatusRetryTimes = 5
-)
+// MethodSendStatusV2 names SendStatusV2 for the circuit breaker and the retry policies
+const MethodSendStatusV2 = "SendStatusV2"
 
 // defaultClient is the default http.Client.
 var defaultClient = &http.Client{
@@ -45,25 +44,28 @@ var defaultClient = &http.Client{
 	},
 }
 
-// New returns a new client.
-func New(endpoint, id, secret string, skipverify bool, additionalCertsDir string) *HTTPClient {
-	return getClient(endpoint, id, "", NewTokenCache(id, secret), skipverify, additionalCertsDir)
+// New returns a new client. Requests fail over from endpoint to the failover endpoints,
+// in order of preference, on connection errors and 5xx responses.
+func New(endpoint, id, secret string, skipverify bool, additionalCertsDir string, failover ...string) *HTTPClient {
+	return getClient(endpoint, failover, id, "", NewTokenCache(id, secret), skipverify, additionalCertsDir)
 }
 
-func NewFromToken(endpoint, id, token string, skipverify bool, additionalCertsDir string) *HTTPClient {
-	return getClient(endpoint, id, token, nil, skipverify, additionalCertsDir)
+func NewFromToken(endpoint, id, token string, skipverify bool, additionalCertsDir string, failover ...string) *HTTPClient {
+	return getClient(endpoint, failover, id, token, nil, skipverify, additionalCertsDir)
 }
 
-func getClient(endpoint, id, token string, cache *TokenCache, skipverify bool, additionalCertsDir string) *HTTPClient {
+func getClient(endpoint string, failover []string, id, token string, cache *TokenCache, skipverify bool, additionalCertsDir string) *HTTPClient {
 	log := logrus.New()
 	httpClient := &HTTPClient{
 		Logger:            log,
 		Endpoint:          endpoint,
+		endpoints:         managerEndpoints(endpoint, failover),
 		SkipVerify:        skipverify,
 		AccountID:         id,
 		Client:            defaultClient,
 		AccountTokenCache: cache,
 		Token:             token,
+		Breaker:           NewCircuitBreaker(breakerThreshold, breakerCooldown),
 	}
 
 	// Load mTLS certificates if available
@@ -169,6 +171,38 @@ type HTTPClient struct {
 	AccountTokenCache *TokenCache
 	SkipVerify        bool
 	Token             string
+	// Breaker fails calls fast while the manager keeps failing. It is disabled if nil.
+	Breaker *CircuitBreaker
+	// RetryPolicies overrides the retry policy of a method, which is named after the methods of
+	// client.Client. Methods without one use DefaultRetryPolicy.
+	RetryPolicies map[string]RetryPolicy
+
+	retryMu sync.Mutex
+
+	endpoints    []string // manager endpoints in order of preference
+	failoverOnce sync.Once
+	failover     *failover
+}
+
+var (
+	_ client.Pauser         = (*HTTPClient)(nil)
+	_ client.TokenRefresher = (*HTTPClient)(nil)
+	_ client.StatusCoder    = (*Error)(nil)
+)
+
+// RefreshToken drops the cached account token, so that the next request is sent with a new one.
+// It has no effect on a client created with a fixed token.
+func (p *HTTPClient) RefreshToken() {
+	if p.AccountTokenCache != nil {
+		p.AccountTokenCache.Invalidate()
+	}
+}
+
+// Paused returns true while the circuit breaker keeps calls to the
+// endpoint from reaching the manager. It returns false again once the
+// cooldown is over, so that the next call goes through as the probe.
+func (p *HTTPClient) Paused(endpoint string) bool {
+	return p.Breaker != nil && p.Breaker.State(endpoint) == BreakerOpen
 }
 
 // Register registers the runner with the manager
@@ -176,23 +210,32 @@ func (p *HTTPClient) Register(ctx context.Context, r *client.RegisterRequest) (*
 	req := r
 	resp := &client.RegisterResponse{}
 	path := fmt.Sprintf(registerEndpoint, p.AccountID)
-	_, err := p.retry(ctx, path, "POST", req, resp, createBackoff(ctx, registerTimeout), true) //nolint: bodyclose
+	_, err := p.retry(ctx, client.MethodRegister, path, "POST", req, resp) //nolint: bodyclose
 	return resp, err
 }
 
+// Unregister tells the manager that the runner is shutting down
+func (p *HTTPClient) Unregister(ctx context.Context, r *client.RegisterRequest) error {
+	req := r
+	path := fmt.Sprintf(unregisterEndpoint, p.AccountID)
+	_, err := p.retry(ctx, client.MethodUnregister, path, "POST", req, nil) //nolint: bodyclose
+	return err
+}
+
 // Heartbeat sends a periodic heartbeat to the server
-func (p *HTTPClient) Heartbeat(ctx context.Context, r *client.RegisterRequest) error {
+func (p *HTTPClient) Heartbeat(ctx context.Context, r *client.RegisterRequest) (*client.HeartbeatResponse, error) {
 	req := r
+	resp := &client.HeartbeatResponse{}
 	path := fmt.Sprintf(heartbeatEndpoint, p.AccountID)
-	_, err := p.do(ctx, path, "POST", req, nil)
-	return err
+	_, err := p.retry(ctx, client.MethodHeartbeat, path, "POST", req, resp)
+	return resp, err
 }
 
 // RegisterCapacity registers maximum number of CI Stages that can run on the host
 func (p *HTTPClient) RegisterCapacity(ctx context.Context, delID string, r *client.DelegateCapacity) error {
 	req := r
 	path := fmt.Sprintf(delegateCapacityEndpoint, delID, p.AccountID)
-	_, err := p.do(ctx, path, "POST", req, nil)
+	_, err := p.retry(ctx, client.MethodRegisterCapacity, path, "POST", req, nil)
 	return err
 }
 
@@ -200,7 +243,7 @@ func (p *HTTPClient) RegisterCapacity(ctx context.Context, delID string, r *clie
 func (p *HTTPClient) GetTaskEvents(ctx context.Context, id string) (*client.TaskEventsResponse, error) {
 	path := fmt.Sprintf(taskPollEndpoint, id, p.AccountID)
 	events := &client.TaskEventsResponse{}
-	_, err := p.do(ctx, path, "GET", nil, events)
+	_, err := p.retry(ctx, client.MethodGetTaskEvents, path, "GET", nil, events)
 	return events, err
 }
 
@@ -208,98 +251,92 @@ func (p *HTTPClient) GetTaskEvents(ctx context.Context, id string) (*client.Task
 func (p *HTTPClient) Acquire(ctx context.Context, delegateID, taskID string) (*client.Task, error) {
 	path := fmt.Sprintf(taskAcquireEndpoint, delegateID, taskID, p.AccountID, delegateID)
 	task := &client.Task{}
-	_, err := p.do(ctx, path, "PUT", nil, task)
+	_, server, err := p.retryWithServer(ctx, client.MethodAcquire, path, "PUT", nil, task) //nolint: bodyclose
+	if err == nil {
+		// the status of the task has to be reported to the manager which handed it out
+		p.servers().pin(taskID, server, time.Duration(task.Timeout)*time.Millisecond)
+	}
 	return task, err
 }
 
 // SendStatus updates the status of a task
 func (p *HTTPClient) SendStatus(ctx context.Context, delegateID, taskID string, r *client.TaskResponse) error {
+	ctx = p.pinned(ctx, taskID)
+	defer p.servers().unpin(taskID)
 	path := fmt.Sprintf(taskStatusEndpoint, taskID, delegateID, p.AccountID)
 	req := r
-	retryNumber := 0
-	var err error
-	for retryNumber < sendStatusRetryTimes {
-		_, err = p.retry(ctx, path, "POST", req, nil, createBackoff(ctx, taskEventsTimeout), true) //nolint: bodyclose
-		if err == nil {
-			return nil
-		}
-		retryNumber++
-	}
+	_, err := p.retry(ctx, client.MethodSendStatus, path, "POST", req, nil) //nolint: bodyclose
 	return err
 }
 
 // SendStatusV2 updates the status of a task submitted via Harness Runner (V2 Task Status endpoint)
 func (p *HTTPClient) SendStatusV2(ctx context.Context, runnerID, taskID string, r *client.RunnerTaskResponse) error {
+	ctx = p.pinned(ctx, taskID)
+	defer p.servers().unpin(taskID)
 	path := fmt.Sprintf(taskStatusEndpointV2, taskID, runnerID, p.AccountID)
 	req := r
-	retryNumber := 0
-	var err error
-	for retryNumber < sendStatusRetryTimes {
-		_, err = p.retry(ctx, path, "POST", req, nil, createBackoff(ctx, taskEventsTimeout), true) //nolint: bodyclose
-		if err == nil {
-			return nil
-		}
-		retryNumber++
-	}
+	_, err := p.retry(ctx, MethodSendStatusV2, path, "POST", req, nil) //nolint: bodyclose
 	return err
 }
 
 func (p *HTTPClient) SendRunnerStatus(ctx context.Context, delegateID, taskID string, r *client.RunnerTaskResponse) error {
+	ctx = p.pinned(ctx, taskID)
+	defer p.servers().unpin(taskID)
 	path := fmt.Sprintf(runnerTaskStatusEndpoint, taskID, p.AccountID, delegateID)
 	req := r
-	retryNumber := 0
-	var err error
-	for retryNumber < sendStatusRetryTimes {
-		_, err = p.retry(ctx, path, "POST", req, nil, createBackoff(ctx, taskEventsTimeout), true) //nolint: bodyclose
-		if err == nil {
-			return nil
-		}
-		retryNumber++
-	}
+	_, err := p.retry(ctx, client.MethodSendRunnerStatus, path, "POST", req, nil) //nolint: bodyclose
 	return err
 }
 
-func (p *HTTPClient) retry(ctx context.Context, path, method string, in, out interface{}, b backoff.BackOffContext, ignoreStatusCode bool) (*http.Response, error) { //nolint: unparam
+// retry sends a request until it succeeds or the retry policy of the endpoint gives up
+func (p *HTTPClient) retry(ctx context.Context, endpoint, path, method string, in, out interface{}) (*http.Response, error) {
+	res, _, err := p.retryWithServer(ctx, endpoint, path, method, in, out)
+	return res, err
+}
+
+// retryWithServer is retry which also returns the manager endpoint that served the last attempt.
+func (p *HTTPClient) retryWithServer(ctx context.Context, endpoint, path, method string, in, out interface{}) (*http.Response, string, error) {
+	policy := p.retryPolicy(endpoint)
+	b := policy.backoff(ctx)
 	for {
-		res, err := p.do(ctx, path, method, in, out)
+		res, server, err := p.doWithServer(ctx, endpoint, path, method, in, out)
 		// do not retry on Canceled or DeadlineExceeded
 		if ctxErr := ctx.Err(); ctxErr != nil {
 			p.logger().Errorf("http: context canceled")
-			return res, ctxErr
+			return res, server, ctxErr
+		}
+		if err == nil || !policy.retryable(res, err) {
+			return res, server, err
 		}
 
 		duration := b.NextBackOff()
-
-		if res != nil {
-			// Check the response code. We retry on 500-range
-			// responses to allow the server time to recover, as
-			// 500's are typically not permanent errors and may
-			// relate to outages on the server side.
-			if (ignoreStatusCode && err != nil) || res.StatusCode > 501 {
-				p.logger().Errorf("http: server error: re-connect and re-try: %s", err)
-				if duration == backoff.Stop {
-					p.logger().Errorf("max retry limit reached, task status won't be updated")
-					return nil, err
-				}
-				time.Sleep(duration)
-				continue
+		if duration == backoff.Stop {
+			if policy.MaxAttempts != 1 {
+				p.logger().Errorf("http: %s: max retry limit reached: %s", endpoint, err)
 			}
-		} else if err != nil {
-			p.logger().Errorf("http: request error: %s", err)
-			if duration == backoff.Stop {
-				p.logger().Errorf("max retry limit reached, task status won't be updated")
-				return nil, err
+			return res, server, err
+		}
+		if wait, ok := retryAfter(res); ok && policy.HonorRetryAfter {
+			duration = wait
+		}
+		// there is no point in trying again before the circuit lets a probe request through
+		if errors.Is(err, ErrCircuitOpen) && p.Breaker != nil {
+			if wait := p.Breaker.remaining(endpoint); wait > duration {
+				duration = wait
 			}
-			time.Sleep(duration)
-			continue
 		}
-		return res, err
+		p.logger().Errorf("http: %s: request failed, retrying in %s: %s", endpoint, duration, err)
+		if !sleep(ctx, duration) {
+			return res, server, ctx.Err()
+		}
 	}
 }
 
-// do is a helper function that posts a signed http request with
-// the input encoded and response decoded from json.
-func (p *HTTPClient) do(ctx context.Context, path, method string, in, out interface{}) (*http.Response, error) {
+// doWithServer is a helper function that posts a signed http request with
+// the input encoded and response decoded from json. endpoint names the
+// endpoint for the circuit breaker. It also returns the manager endpoint
+// that served the request.
+func (p *HTTPClient) doWithServer(ctx context.Context, endpoint, path, method string, in, out interface{}) (*http.Response, string, error) {
 	var buf bytes.Buffer
 
 	// marshal the input payload into json format and copy
@@ -310,28 +347,7 @@ func (p *HTTPClient) do(ctx context.Context, path, method string, in, out interf
 		}
 	}
 
-	endpoint := p.Endpoint + path
-	req, err := http.NewRequest(method, endpoint, &buf)
-	if err != nil {
-		return nil, err
-	}
-	req = req.WithContext(ctx)
-
-	// the request should include the secret shared between
-	// the agent and server for authorization.
-	token := ""
-	if p.Token != "" {
-		token = p.Token
-	} else {
-		token, err = p.AccountTokenCache.Get()
-		if err != nil {
-			p.logger().Errorf("could not generate account token: %s", err)
-			return nil, err
-		}
-	}
-	req.Header.Add("Authorization", "Delegate "+token)
-	req.Header.Add("Content-Type", "application/json")
-	res, err := p.Client.Do(req)
+	res, server, err := p.send(ctx, endpoint, method, path, buf.Bytes())
 	if res != nil {
 		defer func() {
 			// drain the response body so we can reuse
@@ -343,40 +359,129 @@ func (p *HTTPClient) do(ctx context.Context, path, method string, in, out interf
 		}()
 	}
 	if err != nil {
-		return res, err
+		return res, server, err
 	}
 
 	// if the response body return no content we exit
 	// immediately. We do not read or unmarshal the response
 	// and we do not return an error.
 	if res.StatusCode == 204 {
-		return res, nil
+		return res, server, nil
 	}
 
 	// else read the response body into a byte slice.
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return res, err
+		return res, server, err
 	}
 
 	if res.StatusCode > 299 {
-		// if the response body includes an error message
-		// we should return the error string.
-		if len(body) != 0 {
-			return res, errors.New(
-				string(body),
-			)
+		return res, server, newError(endpoint, method, server+path, res.StatusCode, body)
+	}
+	if out == nil || len(body) == 0 {
+		return res, server, nil
+	}
+	return res, server, json.Unmarshal(body, out)
+}
+
+// send sends a request to the manager and returns the response along with the manager
+// endpoint which served it. On connection errors and 5xx responses, the request is sent
+// to the next endpoint, unless it is pinned to an endpoint or is not safe to send twice.
+// Once an endpoint keeps failing, requests move away from it until its health checks pass again.
+func (p *HTTPClient) send(ctx context.Context, endpoint, method, path string, payload []byte) (*http.Response, string, error) {
+	if p.Breaker != nil {
+		if err := p.Breaker.Allow(endpoint); err != nil {
+			return nil, "", err
+		}
+	}
+	servers := p.servers()
+	server, pinned := serverFrom(ctx)
+	if !pinned {
+		server = servers.current()
+	}
+	tried := map[string]bool{}
+	for {
+		req, err := p.newRequest(ctx, server, method, path, bytes.NewReader(payload))
+		if err != nil {
+			if p.Breaker != nil {
+				p.Breaker.cancel(endpoint)
+			}
+			return nil, server, err
+		}
+		res, err := p.Client.Do(req)
+		failed := err != nil || res.StatusCode >= 500
+		switch {
+		case !failed:
+			servers.up(server)
+		case ctx.Err() == nil:
+			servers.fail(server)
+			p.watchHealth()
+		}
+		if failed && !pinned && ctx.Err() == nil && failsOver(endpoint, err) {
+			tried[server] = true
+			if next, ok := servers.next(tried); ok {
+				p.logger().Warnf("manager endpoint %s failed, failing over to %s", server, next)
+				if res != nil {
+					res.Body.Close()
+				}
+				server = next
+				continue
+			}
+		}
+		if p.Breaker != nil {
+			switch {
+			case err != nil && ctx.Err() != nil:
+				// the request was cancelled by the caller, which says nothing about the manager
+				p.Breaker.cancel(endpoint)
+			default:
+				// connection errors and 5xx responses count as failures of the manager
+				p.Breaker.Record(endpoint, !failed)
+			}
 		}
-		// if the response body is empty we should return
-		// the default status code text.
-		return res, errors.New(
-			http.StatusText(res.StatusCode),
-		)
+		return res, server, err
+	}
+}
+
+// pinned returns a context which sends the requests for a task to the manager
+// endpoint which served its acquire, if it is known.
+func (p *HTTPClient) pinned(ctx context.Context, taskID string) context.Context {
+	if server, ok := p.servers().pinned(taskID); ok {
+		return withServer(ctx, server)
+	}
+	return ctx
+}
+
+// NewRequest returns a request to the current manager endpoint which
+// carries the authorization headers expected from a delegate.
+func (p *HTTPClient) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	server, ok := serverFrom(ctx)
+	if !ok {
+		server = p.servers().current()
+	}
+	return p.newRequest(ctx, server, method, path, body)
+}
+
+func (p *HTTPClient) newRequest(ctx context.Context, server, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, server+path, body)
+	if err != nil {
+		return nil, err
 	}
-	if out == nil {
-		return res, nil
+
+	// the request should include the secret shared between
+	// the agent and server for authorization.
+	token := ""
+	if p.Token != "" {
+		token = p.Token
+	} else {
+		token, err = p.AccountTokenCache.Get()
+		if err != nil {
+			p.logger().Errorf("could not generate account token: %s", err)
+			return nil, err
+		}
 	}
-	return res, json.Unmarshal(body, out)
+	req.Header.Add("Authorization", "Delegate "+token)
+	req.Header.Add("Content-Type", "application/json")
+	return req, nil
 }
 
 // logger is a helper function that returns the default logger
@@ -388,8 +493,14 @@ func (p *HTTPClient) logger() logger.Logger {
 	return p.Logger
 }
 
-func createBackoff(ctx context.Context, maxElapsedTime time.Duration) backoff.BackOffContext {
-	exp := backoff.NewExponentialBackOff()
-	exp.MaxElapsedTime = maxElapsedTime
-	return backoff.WithContext(exp, ctx)
+// sleep waits for d and returns false if ctx is done before
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
 }
@@ -0,0 +1,69 @@
+package delegate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+)
+
+// newTestClient returns a client of the manager served by h, with a fast retry policy for method
+func newTestClient(t *testing.T, h http.HandlerFunc, method string) *HTTPClient {
+	t.Helper()
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	c := NewFromToken(server.URL, "account", "token", false, "")
+	c.Breaker = NewCircuitBreaker(5, 50*time.Millisecond)
+	policy := DefaultRetryPolicy(method)
+	policy.InitialInterval = time.Millisecond
+	policy.MaxInterval = 5 * time.Millisecond
+	policy.MaxElapsedTime = 5 * time.Second
+	c.SetRetryPolicy(method, policy)
+	return c
+}
+
+func TestSendRunnerStatusOutlastsOpenCircuit(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 6 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}, client.MethodSendRunnerStatus)
+
+	err := c.SendRunnerStatus(context.Background(), "delegate", "task", &client.RunnerTaskResponse{ID: "task", Code: client.Success})
+	if err != nil {
+		t.Fatalf("status was not sent: %s", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 7 {
+		t.Errorf("got %d requests, want 7", got)
+	}
+	if state := c.Breaker.State(client.MethodSendRunnerStatus); state != BreakerClosed {
+		t.Errorf("got circuit %s, want it closed after the status was sent", state)
+	}
+}
+
+func TestHeartbeatFailsFastOnOpenCircuit(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, client.MethodHeartbeat)
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.Heartbeat(context.Background(), &client.RegisterRequest{}); err == nil {
+			t.Fatal("heartbeat succeeded, want it to fail")
+		}
+	}
+	if _, err := c.Heartbeat(context.Background(), &client.RegisterRequest{}); err != ErrCircuitOpen {
+		t.Errorf("got error %v, want %v", err, ErrCircuitOpen)
+	}
+	if got := atomic.LoadInt32(&calls); got != 5 {
+		t.Errorf("got %d requests, want 5", got)
+	}
+}
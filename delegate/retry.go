@@ -0,0 +1,170 @@
+package delegate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/wings-software/dlite/client"
+)
+
+// ErrorClass classifies errors of requests which did not get a response from the manager
+type ErrorClass string
+
+const (
+	// ErrorConnection is a request which could not reach the manager, e.g. a refused connection
+	ErrorConnection ErrorClass = "connection"
+	// ErrorTimeout is a request which timed out before the manager responded
+	ErrorTimeout ErrorClass = "timeout"
+	// ErrorCircuitOpen is a request which was not sent because the circuit breaker is open
+	ErrorCircuitOpen ErrorClass = "circuit_open"
+)
+
+// RetryPolicy configures how a call to the manager is retried.
+// The zero value sends a request once without retrying it.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Zero means no limit.
+	MaxAttempts int
+	// MaxElapsedTime stops the retries once this much time has passed since the first attempt.
+	// Zero means no limit.
+	MaxElapsedTime time.Duration
+	// InitialInterval is the wait before the first retry. The wait grows by Multiplier with
+	// every retry, up to MaxInterval.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// Jitter randomizes each wait by up to this fraction of it, e.g. 0.5 for ±50%.
+	Jitter float64
+	// RetryableStatusCodes are the response status codes which are retried
+	RetryableStatusCodes []int
+	// RetryableErrors are the classes of errors without a response which are retried
+	RetryableErrors []ErrorClass
+	// HonorRetryAfter waits for as long as the Retry-After header of a retried response asks
+	// instead of the computed interval.
+	HonorRetryAfter bool
+}
+
+// NoRetry sends a request once
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// DefaultRetryPolicy returns the policy used for a method of client.Client unless the HTTPClient
+// is configured with another one. Registration is retried for 30 seconds, unregistration for
+// 10 seconds and task responses for 5 minutes. Task responses are also retried while the circuit
+// breaker is open, so that the result of a task is not lost to a short outage of the manager.
+// The other calls are not retried, the poller calls them again on its own.
+func DefaultRetryPolicy(method string) RetryPolicy {
+	policy := RetryPolicy{
+		InitialInterval:      backoff.DefaultInitialInterval,
+		MaxInterval:          backoff.DefaultMaxInterval,
+		Multiplier:           backoff.DefaultMultiplier,
+		Jitter:               backoff.DefaultRandomizationFactor,
+		RetryableStatusCodes: append([]int(nil), retryableStatusCodes...),
+		RetryableErrors:      []ErrorClass{ErrorConnection, ErrorTimeout},
+		HonorRetryAfter:      true,
+	}
+	switch method {
+	case client.MethodRegister:
+		policy.MaxElapsedTime = 30 * time.Second
+	case client.MethodUnregister:
+		policy.MaxElapsedTime = 10 * time.Second
+	case client.MethodSendStatus, client.MethodSendRunnerStatus, MethodSendStatusV2:
+		policy.MaxElapsedTime = 5 * time.Minute
+		policy.RetryableErrors = append(policy.RetryableErrors, ErrorCircuitOpen)
+	default:
+		return NoRetry
+	}
+	return policy
+}
+
+// SetRetryPolicy sets the retry policy of a method, which is named after the methods of client.Client
+func (p *HTTPClient) SetRetryPolicy(method string, policy RetryPolicy) {
+	p.retryMu.Lock()
+	defer p.retryMu.Unlock()
+	if p.RetryPolicies == nil {
+		p.RetryPolicies = map[string]RetryPolicy{}
+	}
+	p.RetryPolicies[method] = policy
+}
+
+// retryPolicy returns the retry policy of a method
+func (p *HTTPClient) retryPolicy(method string) RetryPolicy {
+	p.retryMu.Lock()
+	defer p.retryMu.Unlock()
+	if policy, ok := p.RetryPolicies[method]; ok {
+		return policy
+	}
+	return DefaultRetryPolicy(method)
+}
+
+// backoff returns the backoff between the attempts of a call
+func (r RetryPolicy) backoff(ctx context.Context) backoff.BackOffContext {
+	exp := backoff.NewExponentialBackOff()
+	exp.InitialInterval = r.InitialInterval
+	exp.MaxInterval = r.MaxInterval
+	exp.Multiplier = r.Multiplier
+	exp.RandomizationFactor = r.Jitter
+	exp.MaxElapsedTime = r.MaxElapsedTime
+	exp.Reset()
+	var b backoff.BackOff = exp
+	if r.MaxAttempts > 0 {
+		b = backoff.WithMaxRetries(b, uint64(r.MaxAttempts-1))
+	}
+	return backoff.WithContext(b, ctx)
+}
+
+// retryable returns true if the policy retries a request which ended with res and err
+func (r RetryPolicy) retryable(res *http.Response, err error) bool {
+	if res != nil {
+		for _, code := range r.RetryableStatusCodes {
+			if res.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+	class := classify(err)
+	for _, c := range r.RetryableErrors {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// classify returns the class of an error of a request which did not get a response
+func classify(err error) ErrorClass {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	default:
+		return ErrorConnection
+	}
+}
+
+// retryAfter returns the wait asked for by the Retry-After header of a response, if any
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -50,3 +50,8 @@ func (t *TokenCache) Get() (string, error) {
 	t.c.Set(t.id, token, t.expiry/2)
 	return token, nil
 }
+
+// Invalidate drops the cached token, so that Get creates a new one
+func (t *TokenCache) Invalidate() {
+	t.c.Delete(t.id)
+}
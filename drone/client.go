@@ -0,0 +1,360 @@
+// Package drone implements client.Client on top of the runner RPC protocol of the Drone
+// server, so that the same router and task handlers can execute Drone pipeline stages.
+//
+// A pending stage is mapped onto a task event, and acquiring the task accepts the stage
+// and fetches its details. The task data is the stage context returned by the server
+// (build, repository, stage, secrets, config and netrc). The status sent for the task
+// completes the stage. Handlers report the progress of the steps of the stage with
+// UpdateStep and upload their logs with Upload.
+package drone
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/logger"
+)
+
+const (
+	pingEndpoint   = "/rpc/v2/ping"
+	stagesEndpoint = "/rpc/v2/stage"
+	stageEndpoint  = "/rpc/v2/stage/%s"
+	acceptEndpoint = "/rpc/v2/stage/%s?machine=%s"
+	stepEndpoint   = "/rpc/v2/step/%d"
+	uploadEndpoint = "/rpc/v2/step/%d/logs/upload"
+)
+
+// StageTaskType is the task type Drone stages are routed with unless another one is configured
+const StageTaskType = "DRONE_STAGE"
+
+// Drone stage statuses
+const (
+	statusRunning = "running"
+	statusPassing = "success"
+	statusFailing = "failure"
+	statusError   = "error"
+	statusKilled  = "killed"
+)
+
+// Filter selects the stages the runner can execute
+type Filter struct {
+	Kind    string            `json:"kind,omitempty"`
+	Type    string            `json:"type,omitempty"`
+	OS      string            `json:"os,omitempty"`
+	Arch    string            `json:"arch,omitempty"`
+	Variant string            `json:"variant,omitempty"`
+	Kernel  string            `json:"kernel,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// Step is a step of a stage, as listed in the stage of the task data. It is sent back to the
+// server as a whole, so it carries all the fields of the step.
+type Step struct {
+	ID        int64    `json:"id"`
+	StageID   int64    `json:"step_id"`
+	Number    int      `json:"number"`
+	Name      string   `json:"name"`
+	Status    string   `json:"status"`
+	Error     string   `json:"error,omitempty"`
+	ErrIgnore bool     `json:"errignore,omitempty"`
+	ExitCode  int      `json:"exit_code"`
+	Started   int64    `json:"started,omitempty"`
+	Stopped   int64    `json:"stopped,omitempty"`
+	Version   int64    `json:"version"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	Image     string   `json:"image,omitempty"`
+	Detached  bool     `json:"detached,omitempty"`
+	Schema    string   `json:"schema,omitempty"`
+}
+
+// Line is a line of the logs of a step
+type Line struct {
+	Number    int    `json:"pos"`
+	Message   string `json:"out"`
+	Timestamp int64  `json:"time"` // seconds since the step started
+}
+
+// Client talks to the Drone server using the runner RPC protocol
+type Client struct {
+	Client   *http.Client
+	Logger   logger.Logger
+	Endpoint string
+	Secret   string
+	// Filter selects the stages requested from the server
+	Filter Filter
+	// TaskType is the task type stages are routed with. Defaults to StageTaskType.
+	TaskType string
+
+	mu     sync.Mutex
+	stages map[string]map[string]interface{} // accepted stages by ID, as sent by the server
+}
+
+var _ client.Client = (*Client)(nil)
+
+// New returns a client for the Drone server at endpoint, authenticated with the shared RPC secret
+func New(endpoint, secret string, skipverify bool) *Client {
+	c := &Client{
+		Client:   http.DefaultClient,
+		Endpoint: endpoint,
+		Secret:   secret,
+		Filter:   Filter{Kind: "pipeline"},
+		TaskType: StageTaskType,
+		stages:   map[string]map[string]interface{}{},
+	}
+	if skipverify {
+		c.Client = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true, //nolint:gosec
+				},
+			},
+		}
+	}
+	return c
+}
+
+// Register checks the connection to the server. Drone runners do not register, so the
+// name of the runner is used as the delegate ID. It is passed to the server as the
+// machine which accepts the stages.
+func (c *Client) Register(ctx context.Context, r *client.RegisterRequest) (*client.RegisterResponse, error) {
+	if err := c.do(ctx, pingEndpoint, "GET", nil, nil); err != nil {
+		return nil, err
+	}
+	id := r.DelegateName
+	if id == "" {
+		id = r.HostName
+	}
+	return &client.RegisterResponse{Resource: client.RegistrationData{DelegateID: id}}, nil
+}
+
+// Unregister is a no-op, Drone runners do not register
+func (c *Client) Unregister(ctx context.Context, r *client.RegisterRequest) error {
+	return nil
+}
+
+// Heartbeat pings the server
+func (c *Client) Heartbeat(ctx context.Context, r *client.RegisterRequest) (*client.HeartbeatResponse, error) {
+	if err := c.do(ctx, pingEndpoint, "GET", nil, nil); err != nil {
+		return nil, err
+	}
+	return &client.HeartbeatResponse{Resource: client.HeartbeatData{DelegateID: r.ID}}, nil
+}
+
+// GetTaskEvents asks the server for a pending stage matching the filter. The server holds
+// the request until a stage is available, so running out of time is not an error.
+func (c *Client) GetTaskEvents(ctx context.Context, delegateID string) (*client.TaskEventsResponse, error) {
+	stage := map[string]interface{}{}
+	err := c.do(ctx, stagesEndpoint, "POST", &c.Filter, &stage)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &client.TaskEventsResponse{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	id := stageID(stage)
+	if id == "" {
+		return &client.TaskEventsResponse{}, nil
+	}
+	return &client.TaskEventsResponse{TaskEvents: []*client.TaskEvent{{TaskID: id, TaskType: c.taskType()}}}, nil
+}
+
+// Acquire accepts the stage, fetches its details and marks it as running
+func (c *Client) Acquire(ctx context.Context, delegateID, taskID string) (*client.Task, error) {
+	stage := map[string]interface{}{}
+	if err := c.do(ctx, fmt.Sprintf(acceptEndpoint, taskID, delegateID), "POST", nil, &stage); err != nil {
+		return nil, err
+	}
+	var details json.RawMessage
+	if err := c.do(ctx, fmt.Sprintf(stageEndpoint, taskID), "GET", nil, &details); err != nil {
+		return nil, err
+	}
+	stage["status"] = statusRunning
+	stage["started"] = time.Now().Unix()
+	stage["machine"] = delegateID
+	if err := c.update(ctx, taskID, stage); err != nil {
+		return nil, err
+	}
+	return &client.Task{
+		ID:             taskID,
+		Type:           c.taskType(),
+		Data:           details,
+		Async:          true,
+		RunnerResponse: true,
+		Timeout:        timeout(details),
+		DelegateInfo:   client.DelegateInfo{ID: delegateID},
+	}, nil
+}
+
+// SendStatus completes the stage with the status of the task
+func (c *Client) SendStatus(ctx context.Context, delegateID, taskID string, r *client.TaskResponse) error {
+	return c.complete(ctx, taskID, client.ResponseCode(r.Code), "")
+}
+
+// SendRunnerStatus completes the stage with the status of the task
+func (c *Client) SendRunnerStatus(ctx context.Context, delegateID, taskID string, r *client.RunnerTaskResponse) error {
+	return c.complete(ctx, taskID, r.Code, r.Error)
+}
+
+// RegisterCapacity is a no-op, the Drone server does not track the capacity of runners
+func (c *Client) RegisterCapacity(ctx context.Context, delegateID string, r *client.DelegateCapacity) error {
+	return nil
+}
+
+// UpdateStep sends the step to the server, e.g. once it starts running or completes.
+// The step is updated with the version returned by the server, which the server uses
+// for optimistic locking, so that it can be sent again.
+func (c *Client) UpdateStep(ctx context.Context, step *Step) error {
+	return c.do(ctx, fmt.Sprintf(stepEndpoint, step.ID), "PUT", step, step)
+}
+
+// Upload uploads the logs of a step once it has completed
+func (c *Client) Upload(ctx context.Context, stepID int64, lines []*Line) error {
+	if lines == nil {
+		lines = []*Line{}
+	}
+	return c.do(ctx, fmt.Sprintf(uploadEndpoint, stepID), "POST", lines, nil)
+}
+
+// complete updates the stage with its final status
+func (c *Client) complete(ctx context.Context, taskID string, code client.ResponseCode, errorMsg string) error {
+	c.mu.Lock()
+	stage, ok := c.stages[taskID]
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("stage %s has not been accepted by this runner", taskID)
+	}
+	stage["status"] = status(code)
+	stage["stopped"] = time.Now().Unix()
+	if errorMsg != "" {
+		stage["error"] = errorMsg
+	}
+	if err := c.update(ctx, taskID, stage); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.stages, taskID)
+	c.mu.Unlock()
+	return nil
+}
+
+// update sends the stage to the server and keeps the version it returns,
+// which the server uses for optimistic locking.
+func (c *Client) update(ctx context.Context, taskID string, stage map[string]interface{}) error {
+	updated := map[string]interface{}{}
+	if err := c.do(ctx, fmt.Sprintf(stageEndpoint, taskID), "PUT", stage, &updated); err != nil {
+		return err
+	}
+	if len(updated) == 0 {
+		updated = stage
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stages == nil {
+		c.stages = map[string]map[string]interface{}{}
+	}
+	c.stages[taskID] = updated
+	return nil
+}
+
+func (c *Client) taskType() string {
+	if c.TaskType == "" {
+		return StageTaskType
+	}
+	return c.TaskType
+}
+
+// do sends a request to the server with the input encoded and the response decoded from json
+func (c *Client) do(ctx context.Context, path, method string, in, out interface{}) error {
+	var buf bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&buf).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.Endpoint+path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Drone-Token", c.Secret)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode > 299 {
+		c.logger().Errorf("drone: %s %s failed with status %d: %s", method, path, res.StatusCode, body)
+		msg := http.StatusText(res.StatusCode)
+		if len(body) != 0 {
+			msg = string(body)
+		}
+		// the status lets the poller tell apart the errors, e.g. a stage which no longer exists
+		return &client.StatusError{Code: res.StatusCode, Err: errors.New(msg)}
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// logger returns the default logger if a custom logger is not defined
+func (c *Client) logger() logger.Logger {
+	if c.Logger == nil {
+		return logger.Discard()
+	}
+	return c.Logger
+}
+
+// status maps a task response code to a stage status
+func status(code client.ResponseCode) string {
+	switch code {
+	case client.Success:
+		return statusPassing
+	case client.Aborted:
+		return statusKilled
+	case client.Failure:
+		return statusFailing
+	default:
+		return statusError
+	}
+}
+
+// stageID returns the ID of a stage sent by the server, or an empty string if there is none
+func stageID(stage map[string]interface{}) string {
+	id, ok := stage["id"].(float64)
+	if !ok || id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(id), 10)
+}
+
+// timeout returns the timeout of the repository the stage belongs to, in milliseconds
+func timeout(details json.RawMessage) int {
+	v := struct {
+		Repo struct {
+			Timeout int64 `json:"timeout"` // minutes
+		} `json:"repository"`
+	}{}
+	if err := json.Unmarshal(details, &v); err != nil {
+		return 0
+	}
+	return int(time.Duration(v.Repo.Timeout) * time.Minute / time.Millisecond)
+}
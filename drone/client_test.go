@@ -0,0 +1,180 @@
+package drone_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/drone"
+	"github.com/wings-software/dlite/drone/dronetest"
+	"github.com/wings-software/dlite/poller"
+	"github.com/wings-software/dlite/router"
+	"github.com/wings-software/dlite/task"
+)
+
+// newTestClient returns a client of the fake Drone server s
+func newTestClient(t *testing.T, s *dronetest.Server) *drone.Client {
+	t.Helper()
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return drone.New(server.URL, "secret", false)
+}
+
+// runSteps is a stage handler which runs every step of the stage, reporting its
+// progress and uploading a line of logs
+func runSteps(c *drone.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := &client.Task{}
+		if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		details := struct {
+			Stage struct {
+				Steps []*drone.Step `json:"steps"`
+			} `json:"stage"`
+		}{}
+		if err := json.Unmarshal(t.Data, &details); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, step := range details.Stage.Steps {
+			step.Status = "running"
+			step.Started = time.Now().Unix()
+			if err := c.UpdateStep(r.Context(), step); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			lines := []*drone.Line{{Number: 0, Message: "running " + step.Name}}
+			if err := c.Upload(r.Context(), step.ID, lines); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			step.Status = "success"
+			step.Stopped = time.Now().Unix()
+			if err := c.UpdateStep(r.Context(), step); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestPollerRunsStages(t *testing.T) {
+	s := dronetest.New("secret")
+	c := newTestClient(t, s)
+	r := router.NewRouter(map[string]task.Handler{drone.StageTaskType: runSteps(c)})
+	p := poller.New("", "", "runner", nil, c, r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	info, err := p.Register(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := s.Enqueue(dronetest.Stage{
+		Name:  "build",
+		Kind:  "pipeline",
+		Steps: []*drone.Step{{Number: 1, Name: "clone"}, {Number: 2, Name: "test"}},
+	})
+	done := make(chan error, 1)
+	go func() { done <- p.Poll(ctx, 1, info.ID, 10*time.Millisecond) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if stage, _ := s.Stage(id); stage.Status == "success" {
+			break
+		}
+		if time.Now().After(deadline) {
+			stage, _ := s.Stage(id)
+			t.Fatalf("stage is %s, want it to pass", stage.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("poll failed: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for poll to return")
+	}
+
+	stage, _ := s.Stage(id)
+	if stage.Machine != info.ID {
+		t.Errorf("stage was accepted by %q, want %q", stage.Machine, info.ID)
+	}
+	if stage.Started == 0 || stage.Stopped == 0 {
+		t.Errorf("stage was not timed, started %d and stopped %d", stage.Started, stage.Stopped)
+	}
+	for _, step := range stage.Steps {
+		if step.Status != "success" {
+			t.Errorf("step %s is %s, want it to pass", step.Name, step.Status)
+		}
+		if lines := s.Logs(step.ID); len(lines) != 1 || lines[0].Message != "running "+step.Name {
+			t.Errorf("got logs %v for step %s", lines, step.Name)
+		}
+	}
+}
+
+func TestAcquireStageAcceptedByAnotherRunner(t *testing.T) {
+	s := dronetest.New("secret")
+	c := newTestClient(t, s)
+	id := s.Enqueue(dronetest.Stage{Name: "build", Kind: "pipeline"})
+
+	evs, err := c.GetTaskEvents(context.Background(), "runner-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs.TaskEvents) != 1 {
+		t.Fatalf("got %d task events, want 1", len(evs.TaskEvents))
+	}
+	if _, err := c.Acquire(context.Background(), "runner-1", evs.TaskEvents[0].TaskID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Acquire(context.Background(), "runner-2", evs.TaskEvents[0].TaskID)
+	if code, _ := client.StatusCode(err); code != http.StatusConflict {
+		t.Errorf("got error %v, want a conflict", err)
+	}
+	if stage, _ := s.Stage(id); stage.Machine != "runner-1" || stage.Status != "running" {
+		t.Errorf("got stage %s on %q, want it running on runner-1", stage.Status, stage.Machine)
+	}
+}
+
+func TestUpdateStepKeepsVersion(t *testing.T) {
+	s := dronetest.New("secret")
+	c := newTestClient(t, s)
+	id := s.Enqueue(dronetest.Stage{Name: "build", Steps: []*drone.Step{{Number: 1, Name: "clone"}}})
+	stage, _ := s.Stage(id)
+	step := stage.Steps[0]
+
+	for _, status := range []string{"running", "success"} {
+		step.Status = status
+		if err := c.UpdateStep(context.Background(), step); err != nil {
+			t.Fatalf("could not update the step to %s: %s", status, err)
+		}
+	}
+	if stage, _ := s.Stage(id); stage.Steps[0].Status != "success" {
+		t.Errorf("step is %s, want it to pass", stage.Steps[0].Status)
+	}
+	step.Version--
+	if err := c.UpdateStep(context.Background(), step); err == nil {
+		t.Error("updated a step with a stale version")
+	}
+}
+
+func TestWrongSecret(t *testing.T) {
+	s := dronetest.New("secret")
+	c := newTestClient(t, s)
+	c.Secret = "wrong"
+
+	if _, err := c.Register(context.Background(), &client.RegisterRequest{DelegateName: "runner"}); !client.IsUnauthorized(err) {
+		t.Errorf("got error %v, want an unauthorized error", err)
+	}
+}
@@ -0,0 +1,329 @@
+// Package dronetest provides a fake Drone server which hands out stages to runners over the
+// runner RPC protocol, for testing drone.Client and the poller without a Drone server.
+package dronetest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wings-software/dlite/drone"
+	"github.com/wings-software/dlite/httphelper"
+)
+
+// Time a request for a stage is held open for when no stage is pending
+var requestWait = 30 * time.Second
+
+// Stage is a stage queued on the fake server, along with what the runner reported
+type Stage struct {
+	ID      int64         `json:"id"`
+	Number  int           `json:"number"`
+	Name    string        `json:"name"`
+	Kind    string        `json:"kind,omitempty"`
+	Type    string        `json:"type,omitempty"`
+	OS      string        `json:"os,omitempty"`
+	Arch    string        `json:"arch,omitempty"`
+	Status  string        `json:"status"`
+	Error   string        `json:"error,omitempty"`
+	Machine string        `json:"machine,omitempty"`
+	Started int64         `json:"started,omitempty"`
+	Stopped int64         `json:"stopped,omitempty"`
+	Version int64         `json:"version"`
+	Steps   []*drone.Step `json:"steps,omitempty"`
+	Timeout int64         `json:"-"` // timeout of the repository, in minutes
+	Config  string        `json:"-"` // pipeline configuration
+}
+
+// Server fakes the runner RPC endpoints of the Drone server
+type Server struct {
+	// Secret is the RPC secret runners have to send
+	Secret string
+
+	mu      sync.Mutex
+	stages  map[int64]*Stage
+	order   []int64
+	steps   map[int64]*drone.Step
+	logs    map[int64][]*drone.Line
+	nextID  int64
+	changed chan struct{} // closed and replaced when stages are enqueued
+	mux     *http.ServeMux
+}
+
+// New returns a fake Drone server with no stages, which accepts runners sending secret
+func New(secret string) *Server {
+	s := &Server{
+		Secret:  secret,
+		stages:  map[int64]*Stage{},
+		steps:   map[int64]*drone.Step{},
+		logs:    map[int64][]*drone.Line{},
+		changed: make(chan struct{}),
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/rpc/v2/ping", s.authorized(s.handlePing))
+	s.mux.HandleFunc("/rpc/v2/stage", s.authorized(s.handleRequest))
+	s.mux.HandleFunc("/rpc/v2/stage/", s.authorized(s.handleStage))
+	s.mux.HandleFunc("/rpc/v2/step/", s.authorized(s.handleStep))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Enqueue queues a pending stage and returns its ID. The stage and its steps get generated IDs.
+func (s *Server) Enqueue(stage Stage) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	stage.ID = s.nextID
+	stage.Status = "pending"
+	stage.Version = 1
+	var steps []*drone.Step
+	for _, step := range stage.Steps {
+		step := *step
+		s.nextID++
+		step.ID = s.nextID
+		step.StageID = stage.ID
+		step.Status = "pending"
+		step.Version = 1
+		s.steps[step.ID] = &step
+		steps = append(steps, &step)
+	}
+	stage.Steps = steps
+	s.stages[stage.ID] = &stage
+	s.order = append(s.order, stage.ID)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return stage.ID
+}
+
+// Stage returns a snapshot of a stage along with its steps
+func (s *Server) Stage(id int64) (Stage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stage, ok := s.stages[id]
+	if !ok {
+		return Stage{}, false
+	}
+	return s.snapshot(stage), true
+}
+
+// Logs returns the log lines uploaded for a step
+func (s *Server) Logs(stepID int64) []drone.Line {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lines []drone.Line
+	for _, line := range s.logs[stepID] {
+		lines = append(lines, *line)
+	}
+	return lines
+}
+
+// GET /rpc/v2/ping
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// POST /rpc/v2/stage holds the request until a pending stage matches the filter of the
+// runner, or responds with 204 No Content once the wait time is over
+func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	filter := &drone.Filter{}
+	if !decode(w, r, http.MethodPost, filter) {
+		return
+	}
+	timeout := time.NewTimer(requestWait)
+	defer timeout.Stop()
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		var pending *Stage
+		for _, id := range s.order {
+			if stage := s.stages[id]; stage.Status == "pending" && stage.Machine == "" && matches(stage, filter) {
+				pending = stage
+				break
+			}
+		}
+		var snapshot Stage
+		if pending != nil {
+			snapshot = s.snapshot(pending)
+		}
+		s.mu.Unlock()
+		if pending != nil {
+			httphelper.WriteJSON(w, &snapshot, http.StatusOK)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-changed:
+		}
+	}
+}
+
+// POST /rpc/v2/stage/{id}?machine={machine} accepts the stage,
+// GET /rpc/v2/stage/{id} returns the stage context and
+// PUT /rpc/v2/stage/{id} updates the stage
+func (s *Server) handleStage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/rpc/v2/stage/"), 10, 64)
+	if err != nil {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stage, ok := s.stages[id]
+	if !ok {
+		httphelper.WriteNotFound(w, fmt.Errorf("stage %d not found", id))
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		if stage.Machine != "" {
+			writeError(w, fmt.Errorf("stage %d has already been accepted", id), http.StatusConflict)
+			return
+		}
+		stage.Machine = r.URL.Query().Get("machine")
+		stage.Version++
+		httphelper.WriteJSON(w, s.snapshot(stage), http.StatusOK)
+	case http.MethodGet:
+		httphelper.WriteJSON(w, map[string]interface{}{
+			"build":      map[string]interface{}{"id": 1, "number": 1, "status": "running"},
+			"repository": map[string]interface{}{"id": 1, "slug": "octocat/hello-world", "timeout": stage.Timeout},
+			"stage":      s.snapshot(stage),
+			"config":     map[string]interface{}{"data": stage.Config},
+			"secrets":    []interface{}{},
+		}, http.StatusOK)
+	case http.MethodPut:
+		in := &Stage{}
+		if !decode(w, r, http.MethodPut, in) {
+			return
+		}
+		if in.Version != stage.Version {
+			writeError(w, fmt.Errorf("stage %d has been updated concurrently", id), http.StatusConflict)
+			return
+		}
+		stage.Status = in.Status
+		stage.Error = in.Error
+		stage.Machine = in.Machine
+		stage.Started = in.Started
+		stage.Stopped = in.Stopped
+		stage.Version++
+		httphelper.WriteJSON(w, s.snapshot(stage), http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// PUT /rpc/v2/step/{id} updates the step and
+// POST /rpc/v2/step/{id}/logs/upload stores the logs of the step
+func (s *Server) handleStep(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/rpc/v2/step/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || (len(parts) != 1 && strings.Join(parts[1:], "/") != "logs/upload") {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	if len(parts) == 1 {
+		s.updateStep(w, r, id)
+		return
+	}
+	var lines []*drone.Line
+	if !decode(w, r, http.MethodPost, &lines) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.steps[id]; !ok {
+		httphelper.WriteNotFound(w, fmt.Errorf("step %d not found", id))
+		return
+	}
+	s.logs[id] = lines
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateStep replaces the step with the one sent by the runner
+func (s *Server) updateStep(w http.ResponseWriter, r *http.Request, id int64) {
+	in := &drone.Step{}
+	if !decode(w, r, http.MethodPut, in) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	step, ok := s.steps[id]
+	if !ok {
+		httphelper.WriteNotFound(w, fmt.Errorf("step %d not found", id))
+		return
+	}
+	if in.Version != step.Version {
+		writeError(w, fmt.Errorf("step %d has been updated concurrently", id), http.StatusConflict)
+		return
+	}
+	*step = *in
+	step.ID = id
+	step.Version++
+	httphelper.WriteJSON(w, step, http.StatusOK)
+}
+
+// snapshot returns a copy of the stage and its steps. It must be called with the lock held.
+func (s *Server) snapshot(stage *Stage) Stage {
+	c := *stage
+	c.Steps = nil
+	for _, step := range stage.Steps {
+		step := *s.steps[step.ID]
+		c.Steps = append(c.Steps, &step)
+	}
+	return c
+}
+
+// authorized rejects requests which do not carry the RPC secret
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Drone-Token") != s.Secret {
+			writeError(w, errors.New("invalid or missing token"), http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// matches returns true if the stage can be executed by a runner with the filter
+func matches(stage *Stage, filter *drone.Filter) bool {
+	for _, f := range [][2]string{
+		{filter.Kind, stage.Kind},
+		{filter.Type, stage.Type},
+		{filter.OS, stage.OS},
+		{filter.Arch, stage.Arch},
+	} {
+		if f[0] != "" && f[1] != "" && f[0] != f[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// decode checks the request method and decodes the json request body into v
+func decode(w http.ResponseWriter, r *http.Request, method string, v interface{}) bool {
+	if r.Method != method {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		httphelper.WriteBadRequest(w, err)
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, err error, status int) {
+	httphelper.WriteJSON(w, struct {
+		Message string `json:"message"`
+	}{err.Error()}, status)
+}
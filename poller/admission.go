@@ -0,0 +1,56 @@
+package poller
+
+import (
+	"time"
+
+	"github.com/wings-software/dlite/client"
+)
+
+// Decision is the verdict of an admission controller on a task event
+type Decision int
+
+const (
+	// Accept lets the poller acquire and execute the task
+	Accept Decision = iota
+	// Reject declines the task. It is not acquired and is ignored by later polls
+	// for a while, so that healthier delegates pick it up.
+	Reject
+	// Defer declines the task for now. It is not acquired but can be picked up again
+	// by a later poll if no other delegate has acquired it in the meantime.
+	Defer
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Accept:
+		return "accept"
+	case Reject:
+		return "reject"
+	case Defer:
+		return "defer"
+	default:
+		return "unknown"
+	}
+}
+
+// RunningTask describes a task which is in flight on the poller
+type RunningTask struct {
+	TaskID   string
+	TaskType string
+	Started  time.Time
+}
+
+// AdmissionController decides whether the poller takes on a task. It is consulted
+// right before the task is acquired, so that it can take the current load into account.
+type AdmissionController interface {
+	// Admit returns the decision for a task event, given the tasks which are in flight
+	Admit(ev *client.TaskEvent, running []RunningTask) Decision
+}
+
+// AdmissionFunc is an adapter to use an ordinary function as an AdmissionController
+type AdmissionFunc func(ev *client.TaskEvent, running []RunningTask) Decision
+
+// Admit calls f(ev, running)
+func (f AdmissionFunc) Admit(ev *client.TaskEvent, running []RunningTask) Decision {
+	return f(ev, running)
+}
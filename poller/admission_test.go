@@ -0,0 +1,129 @@
+package poller
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/client/clienttest"
+)
+
+// countingAdmission returns the decisions of decide and counts how often each task is asked about
+type countingAdmission struct {
+	decide func(ev *client.TaskEvent, asked int) Decision
+
+	mu    sync.Mutex
+	asked map[string]int
+}
+
+func (a *countingAdmission) Admit(ev *client.TaskEvent, running []RunningTask) Decision {
+	a.mu.Lock()
+	if a.asked == nil {
+		a.asked = map[string]int{}
+	}
+	a.asked[ev.TaskID]++
+	asked := a.asked[ev.TaskID]
+	a.mu.Unlock()
+	return a.decide(ev, asked)
+}
+
+func (a *countingAdmission) count(taskID string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.asked[taskID]
+}
+
+// onceSource hands out every task event once, like the sources which push task events,
+// and hands out again the events which are requeued
+type onceSource struct {
+	mu       sync.Mutex
+	evs      []*client.TaskEvent
+	requeued int
+}
+
+func (s *onceSource) Next(ctx context.Context, delegateID string) ([]*client.TaskEvent, error) {
+	if !sleep(ctx, 5*time.Millisecond) {
+		return nil, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	evs := s.evs
+	s.evs = nil
+	return evs, nil
+}
+
+func (s *onceSource) Requeue(evs []*client.TaskEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evs = append(s.evs, evs...)
+	s.requeued += len(evs)
+}
+
+func TestAdmissionRejectIsRemembered(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(
+		&client.Task{ID: "rejected", Type: testTaskType, RunnerResponse: true},
+		&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true},
+	)
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	admission := &countingAdmission{decide: func(ev *client.TaskEvent, asked int) Decision {
+		if ev.TaskID == "rejected" {
+			return Reject
+		}
+		return Accept
+	}}
+	p.SetAdmissionController(admission)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 2)
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	err := c.WaitForStatuses(waitCtx, 1)
+	// give the poller a few more polls to ask about the rejected task again
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	waitFor(t, done, "poll to return")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := admission.count("rejected"); got != 1 {
+		t.Errorf("admission controller was asked %d times about the rejected task, want once", got)
+	}
+	if _, rejected := p.rejectedTasks().Get("rejected"); !rejected {
+		t.Error("rejected task is not remembered")
+	}
+	if got := pendingIDs(c); !reflect.DeepEqual(got, []string{"rejected"}) {
+		t.Errorf("got pending tasks %v, want the rejected task", got)
+	}
+}
+
+func TestAdmissionDeferRequeues(t *testing.T) {
+	c := clienttest.New()
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	source := &onceSource{evs: []*client.TaskEvent{{TaskID: "task-1", TaskType: testTaskType}}}
+	p.SetSource(source)
+	admission := &countingAdmission{decide: func(ev *client.TaskEvent, asked int) Decision {
+		if asked <= 2 {
+			return Defer
+		}
+		return Accept
+	}}
+	p.SetAdmissionController(admission)
+	status := runTask(t, p, c, &client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+
+	if status.TaskID != "task-1" || status.Response.Code != client.Success {
+		t.Errorf("got status %s for %s, want %s for task-1", status.Response.Code, status.TaskID, client.Success)
+	}
+	if got := admission.count("task-1"); got != 3 {
+		t.Errorf("admission controller was asked %d times about the task, want 3", got)
+	}
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if source.requeued != 2 {
+		t.Errorf("got %d requeued events, want 2", source.requeued)
+	}
+}
@@ -0,0 +1,104 @@
+package poller
+
+import "sync"
+
+// Limit restricts how many executor slots the tasks of a type can take up
+type Limit struct {
+	// MaxConcurrent is the maximum number of tasks of the type which run at the same time.
+	// Zero means the tasks are only limited by the number of executors.
+	MaxConcurrent int
+	// Weight is the number of executor slots taken up by a task of the type. Defaults to 1.
+	Weight int
+}
+
+// capacity keeps track of the executor slots which are in use. A slot is reserved
+// when a task event is handed over to the executors and released once the task is done.
+// The slots taken up by a task are released as they were reserved, even if the number
+// of slots changed in the meantime.
+type capacity struct {
+	mu      sync.Mutex
+	size    int
+	used    int
+	limits  map[string]Limit
+	running map[string]int // number of reserved tasks per task type
+	freed   chan struct{}  // signalled whenever slots might have become free
+}
+
+func newCapacity(size int, limits map[string]Limit) *capacity {
+	c := &capacity{size: size, limits: map[string]Limit{}, running: map[string]int{}, freed: make(chan struct{}, 1)}
+	for taskType, limit := range limits {
+		c.limits[taskType] = limit
+	}
+	return c
+}
+
+// free returns the number of slots which are not in use
+func (c *capacity) free() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.used >= c.size {
+		return 0
+	}
+	return c.size - c.used
+}
+
+// reserve takes up the slots needed by a task of the given type and returns how many
+// it took up. It returns false if there are not enough free slots or the task type is
+// at its concurrency limit.
+func (c *capacity) reserve(taskType string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	limit := c.limits[taskType]
+	if limit.MaxConcurrent > 0 && c.running[taskType] >= limit.MaxConcurrent {
+		return 0, false
+	}
+	weight := c.weight(taskType)
+	if c.used+weight > c.size {
+		return 0, false
+	}
+	c.used += weight
+	c.running[taskType]++
+	return weight, true
+}
+
+// release frees up the slots which were taken up by reserve for a task of the given type
+func (c *capacity) release(taskType string, weight int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running[taskType] == 0 {
+		return
+	}
+	c.running[taskType]--
+	c.used -= weight
+	c.signal()
+}
+
+// weight returns the number of slots taken up by a task type. A task never needs more
+// slots than there are in total, otherwise it could never be picked up.
+func (c *capacity) weight(taskType string) int {
+	weight := c.limits[taskType].Weight
+	if weight < 1 {
+		weight = 1
+	}
+	if weight > c.size {
+		weight = c.size
+	}
+	return weight
+}
+
+// resize changes the total number of slots. Slots in use beyond the new size
+// are not taken back, they just do not get reserved again once released.
+func (c *capacity) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = size
+	c.signal()
+}
+
+// signal notifies the poller without blocking. It must be called with the lock held.
+func (c *capacity) signal() {
+	select {
+	case c.freed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,82 @@
+package poller
+
+import (
+	"github.com/wings-software/dlite/client"
+)
+
+// Hook is notified at each stage of the execution of a task. The hooks are called from
+// the executor running the task, so they should return quickly. Embed NopHook to only
+// implement some of the methods.
+type Hook interface {
+	// BeforeAcquire is called before trying to acquire a task. Returning an error
+	// vetoes the task, which is then left for other delegates to pick up.
+	BeforeAcquire(ev *client.TaskEvent) error
+
+	// AfterAcquire is called once the task has been acquired
+	AfterAcquire(ev *client.TaskEvent, task *client.Task)
+
+	// BeforeHandle is called right before the task is handed over to its handler
+	BeforeHandle(ev *client.TaskEvent, task *client.Task)
+
+	// AfterHandle is called once the handler has completed, timed out or been aborted
+	AfterHandle(ev *client.TaskEvent, task *client.Task, out *Outcome)
+
+	// AfterSend is called once the status of the task has been sent to the task server
+	AfterSend(ev *client.TaskEvent, task *client.Task, out *Outcome)
+
+	// OnSendError is called if the status of the task could not be sent to the task server
+	OnSendError(ev *client.TaskEvent, task *client.Task, out *Outcome, err error)
+}
+
+// NopHook implements Hook with methods which do nothing
+type NopHook struct{}
+
+func (NopHook) BeforeAcquire(*client.TaskEvent) error                        { return nil }
+func (NopHook) AfterAcquire(*client.TaskEvent, *client.Task)                 {}
+func (NopHook) BeforeHandle(*client.TaskEvent, *client.Task)                 {}
+func (NopHook) AfterHandle(*client.TaskEvent, *client.Task, *Outcome)        {}
+func (NopHook) AfterSend(*client.TaskEvent, *client.Task, *Outcome)          {}
+func (NopHook) OnSendError(*client.TaskEvent, *client.Task, *Outcome, error) {}
+
+// hookChain calls a list of hooks in order
+type hookChain []Hook
+
+// BeforeAcquire stops at the first hook which vetoes the task
+func (c hookChain) BeforeAcquire(ev *client.TaskEvent) error {
+	for _, h := range c {
+		if err := h.BeforeAcquire(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c hookChain) AfterAcquire(ev *client.TaskEvent, task *client.Task) {
+	for _, h := range c {
+		h.AfterAcquire(ev, task)
+	}
+}
+
+func (c hookChain) BeforeHandle(ev *client.TaskEvent, task *client.Task) {
+	for _, h := range c {
+		h.BeforeHandle(ev, task)
+	}
+}
+
+func (c hookChain) AfterHandle(ev *client.TaskEvent, task *client.Task, out *Outcome) {
+	for _, h := range c {
+		h.AfterHandle(ev, task, out)
+	}
+}
+
+func (c hookChain) AfterSend(ev *client.TaskEvent, task *client.Task, out *Outcome) {
+	for _, h := range c {
+		h.AfterSend(ev, task, out)
+	}
+}
+
+func (c hookChain) OnSendError(ev *client.TaskEvent, task *client.Task, out *Outcome, err error) {
+	for _, h := range c {
+		h.OnSendError(ev, task, out, err)
+	}
+}
@@ -0,0 +1,177 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/client/clienttest"
+)
+
+// callLog records the hook calls of all the hooks of a poller in the order they are made
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, call)
+}
+
+func (l *callLog) recorded() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.calls...)
+}
+
+// recordingHook adds its calls for the task events it gets to a call log, prefixed with its name.
+// It vetoes the tasks for which veto returns an error.
+type recordingHook struct {
+	name string
+	log  *callLog
+	veto func(ev *client.TaskEvent) error
+
+	mu      sync.Mutex
+	sendErr error
+}
+
+func (h *recordingHook) BeforeAcquire(ev *client.TaskEvent) error {
+	h.log.add(h.name + ":BeforeAcquire:" + ev.TaskID)
+	if h.veto != nil {
+		return h.veto(ev)
+	}
+	return nil
+}
+
+func (h *recordingHook) AfterAcquire(ev *client.TaskEvent, task *client.Task) {
+	h.log.add(h.name + ":AfterAcquire:" + ev.TaskID)
+}
+
+func (h *recordingHook) BeforeHandle(ev *client.TaskEvent, task *client.Task) {
+	h.log.add(h.name + ":BeforeHandle:" + ev.TaskID)
+}
+
+func (h *recordingHook) AfterHandle(ev *client.TaskEvent, task *client.Task, out *Outcome) {
+	h.log.add(h.name + ":AfterHandle:" + ev.TaskID)
+}
+
+func (h *recordingHook) AfterSend(ev *client.TaskEvent, task *client.Task, out *Outcome) {
+	h.log.add(h.name + ":AfterSend:" + ev.TaskID)
+}
+
+func (h *recordingHook) OnSendError(ev *client.TaskEvent, task *client.Task, out *Outcome, err error) {
+	h.log.add(h.name + ":OnSendError:" + ev.TaskID)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sendErr = err
+}
+
+func TestHookOrder(t *testing.T) {
+	c := clienttest.New()
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	log := &callLog{}
+	p.AddHook(&recordingHook{name: "first", log: log})
+	p.AddHook(&recordingHook{name: "second", log: log})
+	runTask(t, p, c, &client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	// AfterSend is called once the status has been sent, so it can come after the status is recorded
+	eventually(t, func() bool { return len(log.recorded()) == 10 }, "the hooks to be notified about the sent status")
+
+	want := []string{
+		"first:BeforeAcquire:task-1", "second:BeforeAcquire:task-1",
+		"first:AfterAcquire:task-1", "second:AfterAcquire:task-1",
+		"first:BeforeHandle:task-1", "second:BeforeHandle:task-1",
+		"first:AfterHandle:task-1", "second:AfterHandle:task-1",
+		"first:AfterSend:task-1", "second:AfterSend:task-1",
+	}
+	if got := log.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got hook calls %v, want %v", got, want)
+	}
+}
+
+func TestHookVetoLeavesTaskUnacquired(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(
+		&client.Task{ID: "vetoed", Type: testTaskType, RunnerResponse: true},
+		&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true},
+	)
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	log := &callLog{}
+	vetoing := &recordingHook{name: "vetoing", log: log, veto: func(ev *client.TaskEvent) error {
+		if ev.TaskID == "vetoed" {
+			return errors.New("not on this delegate")
+		}
+		return nil
+	}}
+	next := &recordingHook{name: "next", log: log}
+	p.AddHook(vetoing)
+	p.AddHook(next)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 2)
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	err := c.WaitForStatuses(waitCtx, 1)
+	// the vetoed task is polled again and again, so a slot it kept would soon leave none free
+	eventually(t, func() bool { return usedSlots(p) == 0 }, "the executor slots to be released")
+	cancel()
+	waitFor(t, done, "poll to return")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.RunnerStatuses()[0].TaskID; got != "task-1" {
+		t.Errorf("got status for %s, want task-1", got)
+	}
+	if got := pendingIDs(c); !reflect.DeepEqual(got, []string{"vetoed"}) {
+		t.Errorf("got pending tasks %v, want the vetoed task", got)
+	}
+	for _, call := range log.recorded() {
+		switch call {
+		case "vetoing:BeforeAcquire:vetoed":
+		case "next:BeforeAcquire:vetoed":
+			t.Error("the hooks after the vetoing one were asked about the vetoed task")
+		default:
+			if strings.HasSuffix(call, ":vetoed") {
+				t.Errorf("got hook call %s for the vetoed task", call)
+			}
+		}
+	}
+}
+
+func TestHookOnSendError(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	sendErr := errors.New("could not send the status")
+	c.SetError(clienttest.SendRunnerStatus, sendErr)
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	log := &callLog{}
+	hook := &recordingHook{name: "hook", log: log}
+	p.AddHook(hook)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 1)
+	eventually(t, func() bool {
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		return hook.sendErr != nil
+	}, "the hook to be notified about the send error")
+	cancel()
+	waitFor(t, done, "poll to return")
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if !errors.Is(hook.sendErr, sendErr) {
+		t.Errorf("got send error %v, want %v", hook.sendErr, sendErr)
+	}
+	for _, call := range log.recorded() {
+		if call == "hook:AfterSend:task-1" {
+			t.Error("AfterSend was called for a status which could not be sent")
+		}
+	}
+}
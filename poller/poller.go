@@ -8,13 +8,17 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/icrowley/fake"
+	"github.com/patrickmn/go-cache"
 	"github.com/wings-software/dlite/client"
 	"github.com/wings-software/dlite/router"
+	"github.com/wings-software/dlite/task"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -26,10 +30,26 @@ var (
 	hearbeatInterval  = 10 * time.Second
 	heartbeatTimeout  = 15 * time.Second
 	taskEventsTimeout = 30 * time.Second
+	unregisterTimeout = 15 * time.Second
+	// Number of consecutive heartbeat failures after which the runner registers again
+	maxHeartbeatFailures = 3
+	// Time given to in-flight tasks to finish once a shutdown has been requested
+	drainTimeout = 10 * time.Minute
+	// Maximum time to wait between polls while the task server is erroring
+	maxPollBackoff = 5 * time.Minute
+	// Time for which task events rejected by the admission controller are ignored
+	rejectTTL = time.Minute
+	// Time a task event needs to wait for its priority to go up by one
+	priorityAging = 30 * time.Second
+	// Time after which a task event which is not polled anymore is forgotten
+	seenTTL = 10 * time.Minute
 )
 
 type FilterFn func(*client.TaskEvent) bool
 
+// errDeferred is returned by execute for a task which the admission controller deferred
+var errDeferred = errors.New("task was deferred")
+
 type Poller struct {
 	AccountID     string
 	AccountSecret string
@@ -38,11 +58,58 @@ type Poller struct {
 	Client        client.Client
 	Router        router.Router
 	Filter        FilterFn
+	// DrainTimeout is the grace period given to in-flight tasks to complete after the
+	// context passed to Poll is cancelled. Tasks still running after it are cancelled.
+	DrainTimeout time.Duration
+	// Limits restricts the concurrency of individual task types. Task events whose type is
+	// at its limit are not acquired, so that other delegates can pick them up.
+	Limits map[string]Limit
+	// Priority decides the order in which polled task events are executed. Events are
+	// executed in the order they were polled if it is not set.
+	Priority PriorityFunc
+	// Aging is the time after which a waiting task event gets its priority raised by one
+	Aging time.Duration
+	// Hooks are notified at each stage of the execution of a task
+	Hooks []Hook
+	// Admission decides whether a task is taken on, right before acquiring it
+	Admission AdmissionController
+	// Source delivers the task events to Poll. The task server is polled at the interval
+	// passed to Poll if it is not set.
+	Source EventSource
 	// The Harness manager allows two task acquire calls with the same delegate ID to go through (by design).
 	// We need to make sure two different threads do not acquire the same task.
 	// This map makes sure Acquire() is called only once per task ID. The mapping is removed once the status
-	// for the task has been sent.
+	// for the task has been sent. It maps the task ID to the *inflight state of the task.
 	m sync.Map
+
+	// The delegate ID changes when the runner registers again with the task server
+	idMu sync.RWMutex
+	id   string
+	host string // host name and IP the runner registered with
+	ip   string
+
+	// Executors of the running Poll call
+	poolMu sync.Mutex
+	pool   *pool
+
+	lifecycle lifecycle
+
+	// Task IDs rejected by the admission controller, or which the task server no longer knows
+	rejectedOnce sync.Once
+	rejected     *cache.Cache
+
+	// Time at which every task ID was first polled, for the priority of waiting task events to age
+	seenOnce sync.Once
+	seen     *cache.Cache
+
+	// Delegate IDs which the task server no longer knows, for the heartbeat thread to register again
+	unknownOnce sync.Once
+	unknown     chan string
+
+	// Heartbeat thread of the last registration
+	heartbeatMu   sync.Mutex
+	stopHeartbeat context.CancelFunc
+	heartbeatDone chan struct{}
 }
 
 type DelegateInfo struct {
@@ -60,6 +127,8 @@ func New(accountID, accountSecret, name string, tags []string, c client.Client,
 		Name:          name,
 		Client:        c,
 		Router:        r,
+		DrainTimeout:  drainTimeout,
+		Aging:         priorityAging,
 		m:             sync.Map{},
 	}
 }
@@ -68,6 +137,50 @@ func (p *Poller) SetFilter(filter FilterFn) {
 	p.Filter = filter
 }
 
+func (p *Poller) SetLimits(limits map[string]Limit) {
+	p.Limits = limits
+}
+
+func (p *Poller) SetPriority(priority PriorityFunc, aging time.Duration) {
+	p.Priority = priority
+	p.Aging = aging
+}
+
+func (p *Poller) AddHook(hook Hook) {
+	p.Hooks = append(p.Hooks, hook)
+}
+
+func (p *Poller) SetAdmissionController(admission AdmissionController) {
+	p.Admission = admission
+}
+
+func (p *Poller) SetSource(source EventSource) {
+	p.Source = source
+}
+
+func (p *Poller) SetDrainTimeout(timeout time.Duration) {
+	p.DrainTimeout = timeout
+}
+
+// State returns the current lifecycle state of the poller
+func (p *Poller) State() State {
+	state, _ := p.lifecycle.current()
+	return state
+}
+
+// StateSince returns the current lifecycle state of the poller and the time it entered it
+func (p *Poller) StateSince() (State, time.Time) {
+	return p.lifecycle.current()
+}
+
+// Subscribe returns a channel which receives the lifecycle state changes of the poller,
+// along with a function which ends the subscription and closes the channel. Changes are
+// dropped for a subscriber which does not keep up with them.
+func (p *Poller) Subscribe() (<-chan StateChange, func()) {
+	sub := p.lifecycle.subscribe()
+	return sub, func() { p.lifecycle.unsubscribe(sub) }
+}
+
 // Register registers the runner with the server. The server generates a delegate ID
 // which is returned to the client.
 func (p *Poller) Register(ctx context.Context) (*DelegateInfo, error) {
@@ -77,11 +190,14 @@ func (p *Poller) Register(ctx context.Context) (*DelegateInfo, error) {
 	}
 	host = "dlite-" + strings.ReplaceAll(host, " ", "-")
 	ip := getOutboundIP()
+	p.lifecycle.transition(StateRegistering)
 	id, err := p.register(ctx, hearbeatInterval, ip, host)
 	if err != nil {
+		p.lifecycle.transition(StateIdle)
 		logrus.WithField("ip", ip).WithField("host", host).WithError(err).Error("could not register runner")
 		return nil, err
 	}
+	p.lifecycle.transition(p.active())
 	return &DelegateInfo{
 		ID:   id,
 		Host: host,
@@ -92,69 +208,312 @@ func (p *Poller) Register(ctx context.Context) (*DelegateInfo, error) {
 
 // Poll continually asks the task server for tasks to execute. It executes the tasks by routing
 // them to the correct handler and updating the status of the task to the server.
-// id is the delegate instance ID. It's generated by the server on registration.
+// Task events come from the configured Source, which polls the server every interval by default.
+// id is the delegate instance ID. It's generated by the server on registration. If the runner
+// registers again while polling, the new delegate ID is picked up for all subsequent calls.
+// Once ctx is cancelled, Poll stops picking up new tasks and waits for the in-flight ones
+// to finish and report their status before returning. Tasks which are still running after
+// the drain timeout get their context cancelled. Last, the runner unregisters from the task server.
 func (p *Poller) Poll(ctx context.Context, n int, id string, interval time.Duration) error {
 	var wg sync.WaitGroup
-	events := make(chan *client.TaskEvent, n)
+	p.initDelegateID(id)
+	defer p.lifecycle.transition(StateStopped)
+	// Every event sent to the executors holds a slot until its task is done,
+	// so that the poller only asks for as much work as it can start.
+	slots := newCapacity(n, p.Limits)
+	aging := p.Aging
+	if aging <= 0 {
+		aging = priorityAging
+	}
+	events := newQueue(p.Priority, aging)
+	// Tasks run on a context which is detached from ctx so that a shutdown
+	// does not abandon them midway. It is cancelled if draining times out.
+	taskCtx, cancelTasks := context.WithCancel(context.Background())
+	defer cancelTasks()
+	source := p.Source
+	if source == nil {
+		source = NewPollingSource(p.Client, interval)
+	}
+	// Sources which push task events only deliver them once, so the events which are
+	// not taken on now are handed back to be picked up later
+	requeue := func(evs ...*client.TaskEvent) {
+		if r, ok := source.(Requeuer); ok && len(evs) > 0 && ctx.Err() == nil {
+			r.Requeue(evs)
+		}
+	}
 	// Task event poller
+	wg.Add(1)
 	go func() {
-		pollTimer := time.NewTimer(interval)
+		defer wg.Done()
+		errBackoff := backoff.NewExponentialBackOff()
+		if interval > 0 {
+			errBackoff.InitialInterval = interval
+		}
+		errBackoff.MaxInterval = maxPollBackoff
+		errBackoff.MaxElapsedTime = 0 // keep polling until ctx is done
+		errBackoff.Reset()            // start from the configured initial interval
 		for {
-			pollTimer.Reset(interval)
-			select {
-			case <-ctx.Done():
-				logrus.Error("context canceled")
+			// Only look for task events when there is an executor free to start them
+			for slots.free() == 0 {
+				logrus.Debugln("all executors are busy, waiting for one to be free")
+				select {
+				case <-ctx.Done():
+					logrus.Infoln("stopped polling for task events")
+					return
+				case <-slots.freed:
+				}
+			}
+			// Do not add to the load of a struggling task server while the client is holding off calls to it
+			if pc, ok := p.Client.(client.Pauser); ok && pc.Paused(client.MethodGetTaskEvents) {
+				logrus.Debugln("client has paused calls to the task server, waiting before polling")
+				if !sleep(ctx, interval) {
+					return
+				}
+				continue
+			}
+			delegateID := p.delegateID()
+			evs, err := source.Next(ctx, delegateID)
+			if ctx.Err() != nil {
+				logrus.Infoln("stopped polling for task events")
 				return
-			case <-pollTimer.C:
-				taskEventsCtx, cancelFn := context.WithTimeout(ctx, taskEventsTimeout)
-				tasks, err := p.Client.GetTaskEvents(taskEventsCtx, id)
-				if err != nil {
-					logrus.WithError(err).Errorf("could not query for task events")
+			}
+			if err != nil {
+				p.onError(err)
+				if client.IsNotFound(err) {
+					p.unknownDelegate(delegateID)
 				}
-				cancelFn()
+				// back off while the task server is erroring instead of asking again right away
+				wait := errBackoff.NextBackOff()
+				logrus.WithError(err).Errorf("could not query for task events, retrying in %s", wait)
+				if !sleep(ctx, wait) {
+					return
+				}
+				continue
+			}
+			errBackoff.Reset()
 
-				// Search for a task event matching the filter
-				for _, ev := range tasks.TaskEvents {
-					if p.Filter == nil || p.Filter(ev) {
-						logrus.WithField("task_id", ev.TaskID).Info("trying to acquire task")
-						events <- ev
-					}
+			// Search for task events matching the filter, as long as there are free executors.
+			// The most important events go first, so that they get the capacity left.
+			events.sort(evs, p.firstSeen)
+			reserved := 0
+			var skipped []*client.TaskEvent
+			for _, ev := range evs {
+				if p.Filter != nil && !p.Filter(ev) {
+					skipped = append(skipped, ev)
+					continue
+				}
+				if _, running := p.m.Load(ev.TaskID); running {
+					continue
+				}
+				if _, rejected := p.rejectedTasks().Get(ev.TaskID); rejected {
+					continue
 				}
+				weight, ok := slots.reserve(ev.TaskType)
+				if !ok {
+					logrus.WithField("task_id", ev.TaskID).WithField("task_type", ev.TaskType).
+						Debugln("no capacity left for the task type, leaving the task to other delegates")
+					skipped = append(skipped, ev)
+					continue
+				}
+				logrus.WithField("task_id", ev.TaskID).Info("trying to acquire task")
+				events.push(ev, p.firstSeen(ev), weight)
+				reserved++
+			}
+			if fs, ok := source.(FeedbackSource); ok && len(evs) > 0 {
+				fs.Reserved(reserved)
 			}
+			requeue(skipped...)
 		}
 	}()
 	// Task event executor
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(i int) {
-			for {
-				select {
-				case <-ctx.Done():
-					wg.Done()
-					return
-				case task := <-events:
-					err := p.execute(ctx, id, *task, i)
-					if err != nil {
-						logrus.WithError(err).WithField("task_id", task.TaskID).Errorf("[Thread %d]: could not perform task execution", i)
-					}
-				}
-			}
-		}(i)
-	}
+	pl := newPool(ctx, events, slots, func(ev *client.TaskEvent, i int) {
+		err := p.execute(taskCtx, ctx, p.delegateID(), *ev, i)
+		switch {
+		case errors.Is(err, errDeferred):
+			requeue(ev)
+		case err != nil:
+			logrus.WithError(err).WithField("task_id", ev.TaskID).Errorf("[Thread %d]: could not perform task execution", i)
+		}
+	})
+	pl.resize(n)
+	p.setPool(pl)
+	defer p.setPool(nil)
+	p.lifecycle.transition(StatePolling)
 	logrus.Infof("initialized %d threads successfully and starting polling for tasks", n)
+
+	done := make(chan struct{})
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		p.drain(ctx, done, cancelTasks)
+	}()
 	wg.Wait()
+	pl.wait()
+	close(done)
+	// the drain thread must be done with the lifecycle before the poller is stopped
+	<-drained
+	logrus.Infoln("all in-flight tasks have completed, stopped polling")
+	// The heartbeats outlive the context of Register while tasks are draining. They must be
+	// over before unregistering, or a heartbeat could register the runner again right after.
+	p.stopHeartbeats()
+	p.unregister()
+	return nil
+}
+
+// SetParallelism changes the number of executors of the running Poll call and registers
+// the new capacity with the task server. When shrinking, the executors which are retired
+// finish their current task first.
+func (p *Poller) SetParallelism(ctx context.Context, n int) error {
+	if n < 1 {
+		return errors.New("parallelism must be at least 1")
+	}
+	p.poolMu.Lock()
+	pl := p.pool
+	p.poolMu.Unlock()
+	if pl == nil || !pl.resize(n) {
+		return errors.New("poller is not running")
+	}
+	logrus.Infof("changed the number of threads to %d", n)
+	err := p.Client.RegisterCapacity(ctx, p.delegateID(), &client.DelegateCapacity{MaxBuilds: n})
+	if err != nil {
+		return errors.Wrap(err, "could not register the new capacity")
+	}
 	return nil
 }
 
-// execute tries to acquire the task and executes the handler for it
-func (p *Poller) execute(ctx context.Context, delegateID string, ev client.TaskEvent, i int) error {
+// active returns the state of a healthy poller, depending on whether it is polling
+func (p *Poller) active() State {
+	if p.polling() {
+		return StatePolling
+	}
+	return StateRegistered
+}
+
+// polling returns whether a Poll call is running
+func (p *Poller) polling() bool {
+	p.poolMu.Lock()
+	defer p.poolMu.Unlock()
+	return p.pool != nil
+}
+
+func (p *Poller) setPool(pl *pool) {
+	p.poolMu.Lock()
+	defer p.poolMu.Unlock()
+	p.pool = pl
+}
+
+// drain waits for a shutdown to be requested and then gives the in-flight tasks
+// up to the drain timeout to finish before cancelling them.
+func (p *Poller) drain(ctx context.Context, done <-chan struct{}, cancelTasks context.CancelFunc) {
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	timeout := p.DrainTimeout
+	if timeout <= 0 {
+		timeout = drainTimeout
+	}
+	p.lifecycle.transition(StateDraining)
+	logrus.Infof("shutdown requested, waiting up to %s for in-flight tasks to complete", timeout)
+	drainTimer := time.NewTimer(timeout)
+	defer drainTimer.Stop()
+	select {
+	case <-done:
+	case <-drainTimer.C:
+		logrus.Warnln("drain timeout exceeded, cancelling in-flight tasks")
+		cancelTasks()
+	}
+}
+
+// Abort cancels the execution of an in-flight task, which then gets reported to the
+// task server as aborted. It returns false if the task is not running on this poller.
+func (p *Poller) Abort(taskID string) bool {
+	v, ok := p.m.Load(taskID)
+	if !ok {
+		return false
+	}
+	v.(*inflight).abort()
+	return true
+}
+
+// running returns a snapshot of the tasks in flight, except for the given task
+func (p *Poller) running(except string) []RunningTask {
+	var tasks []RunningTask
+	p.m.Range(func(k, v interface{}) bool {
+		if k.(string) != except {
+			state := v.(*inflight)
+			tasks = append(tasks, RunningTask{TaskID: k.(string), TaskType: state.taskType, Started: state.started})
+		}
+		return true
+	})
+	return tasks
+}
+
+// rejectedTasks returns the cache of task IDs rejected by the admission controller
+func (p *Poller) rejectedTasks() *cache.Cache {
+	p.rejectedOnce.Do(func() {
+		p.rejected = cache.New(rejectTTL, rejectTTL)
+	})
+	return p.rejected
+}
+
+// firstSeen returns the time at which a task event was first polled
+func (p *Poller) firstSeen(ev *client.TaskEvent) time.Time {
+	p.seenOnce.Do(func() {
+		p.seen = cache.New(seenTTL, seenTTL)
+	})
+	seen := time.Now()
+	if v, ok := p.seen.Get(ev.TaskID); ok {
+		seen = v.(time.Time)
+	}
+	// polling the event again keeps it from being forgotten
+	p.seen.SetDefault(ev.TaskID, seen)
+	return seen
+}
+
+// execute tries to acquire the task and executes the handler for it. The task is left
+// to other delegates if pollCtx is done before it is acquired.
+func (p *Poller) execute(ctx, pollCtx context.Context, delegateID string, ev client.TaskEvent, i int) error {
 	taskID := ev.TaskID
-	if _, loaded := p.m.LoadOrStore(taskID, true); loaded {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	state := &inflight{cancel: cancel, taskType: ev.TaskType, started: time.Now()}
+	if _, loaded := p.m.LoadOrStore(taskID, state); loaded {
 		return nil
 	}
 	defer p.m.Delete(taskID)
+	hooks := hookChain(p.Hooks)
+	if err := hooks.BeforeAcquire(&ev); err != nil {
+		logrus.WithError(err).WithField("task_id", taskID).Infoln("task was vetoed before acquiring it")
+		return nil
+	}
+	if p.Admission != nil {
+		decision := p.Admission.Admit(&ev, p.running(taskID))
+		if decision != Accept {
+			logrus.WithField("task_id", taskID).WithField("task_type", ev.TaskType).
+				Infof("admission controller decided to %s the task", decision)
+			if decision == Reject {
+				p.rejectedTasks().SetDefault(taskID, true)
+				return nil
+			}
+			return errDeferred
+		}
+	}
+	// The executors can still pick up an event which was queued right before a shutdown
+	if pollCtx.Err() != nil {
+		logrus.WithField("task_id", taskID).Infoln("shutdown requested, leaving the task to other delegates")
+		return nil
+	}
 	task, err := p.Client.Acquire(ctx, delegateID, taskID)
 	if err != nil {
+		p.onError(err)
+		if client.IsNotFound(err) {
+			// the task is gone, there is no point in trying to acquire it again
+			p.rejectedTasks().SetDefault(taskID, true)
+			logrus.WithError(err).WithField("task_id", taskID).Infoln("task no longer exists, dropping it")
+			return nil
+		}
 		// Log warning error when unable to acquire task
 		// Decrease error rate for dlite when CI_DLITE_DISTRIBUTED FF is enabled
 		logrus.WithError(err).WithField("task_id", taskID).Warnln("failed to acquire task")
@@ -166,26 +525,38 @@ func (p *Poller) execute(ctx context.Context, delegateID string, ev client.TaskE
 		return errors.Wrap(err, "failed to encode task")
 	}
 	logrus.Infof("[Thread %d]: successfully acquired taskID: %s of type: %s", i, taskID, task.Type)
+	hooks.AfterAcquire(&ev, task)
 	if !slices.Contains(p.Router.Routes(), task.Type) { // should not happen
 		logrus.Errorf("[Thread %d]: Task ID of type: %s was never meant to reach this delegate", i, task.Type)
 		return fmt.Errorf("task type not supported by delegate")
 	}
 
+	// The handler gets the task deadline through the request context
+	execCtx, cancel := withTaskDeadline(ctx, task)
+	defer cancel()
+
 	// TODO: Discuss possible better ways to forward the HTTP response to the task for processing
 	// For now, keeping the handler interface consistent with the HTTP handler to allow for possible
 	// extension in the future with CGI, etc.
-	req, err := http.NewRequestWithContext(ctx, "POST", "/", &buf)
+	req, err := http.NewRequestWithContext(execCtx, "POST", "/", &buf)
 	if err != nil {
 		return err
 	}
 
-	writer := NewResponseWriter()
-	p.Router.Route(task.Type).ServeHTTP(writer, req)
+	hooks.BeforeHandle(&ev, task)
+	out := p.handle(execCtx, state, task, req)
+	hooks.AfterHandle(&ev, task, out)
+	switch out.Code {
+	case client.Timeout:
+		logrus.Warnf("[Thread %d]: taskID: %s of type: %s timed out", i, taskID, task.Type)
+	case client.Aborted:
+		logrus.Warnf("[Thread %d]: taskID: %s of type: %s was aborted", i, taskID, task.Type)
+	}
 
 	if task.RunnerResponse {
-		err = p.sendRunnerResponse(task, writer, delegateID, taskID)
+		err = p.sendRunnerResponse(&ev, task, out, delegateID)
 	} else {
-		err = p.sendLegacyResponse(task, writer, delegateID, taskID)
+		err = p.sendLegacyResponse(&ev, task, out, delegateID)
 	}
 
 	if err != nil {
@@ -195,31 +566,159 @@ func (p *Poller) execute(ctx context.Context, delegateID string, ev client.TaskE
 	return nil
 }
 
-func (p *Poller) sendLegacyResponse(task *client.Task, writer *response, delegateID, taskID string) error {
+// handle routes the task to its handler and waits for the handler to return. A panic in
+// the handler is recovered and the task is reported as failed. If the context is done
+// first, the handler is abandoned and the task is reported as timed out or aborted, or
+// as failed if the context was cancelled for any other reason.
+func (p *Poller) handle(ctx context.Context, state *inflight, task *client.Task, req *http.Request) *Outcome {
+	writer := NewResponseWriter()
+	done := make(chan struct{})
+	var recovered interface{}
+	go func() {
+		defer close(done)
+		// A panicking handler must not take down the other tasks running on the delegate
+		defer func() {
+			if recovered = recover(); recovered != nil {
+				logrus.WithField("task_id", task.ID).WithField("task_type", task.Type).
+					Errorf("task handler panicked: %v\n%s", recovered, debug.Stack())
+			}
+		}()
+		p.Router.Route(task.Type).ServeHTTP(writer, req)
+	}()
+	select {
+	case <-done:
+		if recovered != nil {
+			return &Outcome{Code: client.Failure, Error: fmt.Sprintf("task handler panicked: %v", recovered)}
+		}
+		out := &Outcome{Code: responseCode(writer), Status: writer.status, Data: writer.buf.Bytes()}
+		switch {
+		case out.Code == client.Success:
+		case writer.status >= 300:
+			out.Error = fmt.Sprintf("Failed executing task with error code %v", writer.status)
+		default:
+			out.Error = fmt.Sprintf("Failed executing task with response code %s", out.Code)
+		}
+		return out
+	case <-ctx.Done():
+	}
+	if state.isAborted() {
+		return &Outcome{Code: client.Aborted, Error: "task was aborted"}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &Outcome{Code: client.Timeout, Error: fmt.Sprintf("task did not complete within %s", taskTimeout(task))}
+	}
+	return &Outcome{Code: client.Failure, Error: "task execution was cancelled"}
+}
+
+func (p *Poller) sendLegacyResponse(ev *client.TaskEvent, task *client.Task, out *Outcome, delegateID string) error {
+	data := out.Data
+	if len(data) == 0 && out.Error != "" {
+		// The legacy response has no error field, so the reason is sent as the data
+		data, _ = json.Marshal(struct {
+			Error string `json:"error"`
+		}{out.Error})
+	}
 	taskResponse := &client.TaskResponse{
 		ID:   task.ID,
-		Data: writer.buf.Bytes(),
-		Code: "OK",
+		Data: data,
+		Code: string(out.Code),
 		Type: task.Type,
 	}
-	return p.Client.SendStatus(context.Background(), delegateID, taskID, taskResponse)
+	err := p.Client.SendStatus(context.Background(), delegateID, ev.TaskID, taskResponse)
+	p.afterSend(ev, task, out, err)
+	return err
 }
 
-func (p *Poller) sendRunnerResponse(task *client.Task, writer *response, delegateID, taskID string) error {
-	status := client.Success
-	errorMsg := ""
-	if writer.status < 200 && writer.status >= 300 {
-		status = client.Failure
-		errorMsg = fmt.Sprintf("Failed executing task with error code %v", writer.status)
-	}
+func (p *Poller) sendRunnerResponse(ev *client.TaskEvent, task *client.Task, out *Outcome, delegateID string) error {
 	taskResponse := &client.RunnerTaskResponse{
 		ID:    task.ID,
-		Data:  json.RawMessage(writer.buf.Bytes()),
-		Code:  status,
-		Error: errorMsg,
+		Data:  json.RawMessage(out.Data),
+		Code:  out.Code,
+		Error: out.Error,
 		Type:  task.Type,
 	}
-	return p.Client.SendRunnerStatus(context.Background(), delegateID, taskID, taskResponse)
+	err := p.Client.SendRunnerStatus(context.Background(), delegateID, ev.TaskID, taskResponse)
+	p.afterSend(ev, task, out, err)
+	return err
+}
+
+// afterSend notifies the hooks about the result of sending the status of a task
+func (p *Poller) afterSend(ev *client.TaskEvent, task *client.Task, out *Outcome, err error) {
+	if err != nil {
+		p.onError(err)
+		hookChain(p.Hooks).OnSendError(ev, task, out, err)
+		return
+	}
+	hookChain(p.Hooks).AfterSend(ev, task, out)
+}
+
+// inflight is the state of a task which has been picked up by an executor
+type inflight struct {
+	taskType string
+	started  time.Time
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	aborted bool
+}
+
+// abort marks the task as aborted and cancels its execution
+func (t *inflight) abort() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.aborted = true
+	t.cancel()
+}
+
+func (t *inflight) isAborted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.aborted
+}
+
+// Outcome describes how the execution of a task ended
+type Outcome struct {
+	Code   client.ResponseCode
+	Error  string // reason for a task which did not succeed
+	Status int    // status code written by the handler
+	Data   []byte // response written by the handler
+}
+
+// responseCode maps the response written by a handler to the code reported to the task server.
+// An explicit code in the task.ResponseCodeHeader header takes precedence over the status code.
+func responseCode(writer *response) client.ResponseCode {
+	if v := writer.Header().Get(task.ResponseCodeHeader); v != "" {
+		switch code := client.ResponseCode(v); code {
+		case client.Success, client.Failure, client.Timeout, client.Aborted, client.RetryOnOtherDelegate:
+			return code
+		default:
+			logrus.Warnf("ignoring unknown task response code: %s", v)
+		}
+	}
+	switch {
+	case writer.status == 0 || (writer.status >= 200 && writer.status < 300):
+		return client.Success
+	case writer.status == http.StatusRequestTimeout || writer.status == http.StatusGatewayTimeout:
+		return client.Timeout
+	case writer.status == http.StatusServiceUnavailable:
+		return client.RetryOnOtherDelegate
+	default:
+		return client.Failure
+	}
+}
+
+// taskTimeout returns the execution timeout of a task. The task server sends it in milliseconds.
+func taskTimeout(task *client.Task) time.Duration {
+	return time.Duration(task.Timeout) * time.Millisecond
+}
+
+// withTaskDeadline returns a copy of ctx which expires once the task timeout is over.
+// A task without a timeout does not get a deadline.
+func withTaskDeadline(ctx context.Context, task *client.Task) (context.Context, context.CancelFunc) {
+	if task.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, taskTimeout(task))
 }
 
 // Register registers the runner and runs a background thread which keeps pinging the server
@@ -247,34 +746,217 @@ func (p *Poller) register(ctx context.Context, interval time.Duration, ip, host
 	req.ID = resp.Resource.DelegateID
 	logrus.WithField("id", req.ID).WithField("host", req.HostName).
 		WithField("ip", req.IP).Info("registered delegate successfully")
+	p.setDelegateID(req.ID)
+	p.idMu.Lock()
+	p.host, p.ip = host, ip
+	p.idMu.Unlock()
 	p.heartbeat(ctx, req, interval)
 	return resp.Resource.DelegateID, nil
 }
 
-// heartbeat starts a periodic thread in the background which continually pings the server
-func (p *Poller) heartbeat(ctx context.Context, req *client.RegisterRequest, interval time.Duration) {
+// heartbeat starts a periodic thread in the background which continually pings the server.
+// If the server rejects the heartbeats or no longer knows the delegate, the runner is registered again.
+// The thread stops once regCtx is done, unless the poller is polling. Poll then stops it after the
+// in-flight tasks have drained, so that the task server does not consider them lost meanwhile.
+func (p *Poller) heartbeat(regCtx context.Context, req *client.RegisterRequest, interval time.Duration) {
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	p.heartbeatMu.Lock()
+	if p.stopHeartbeat != nil {
+		p.stopHeartbeat() // the runner registered again, the previous thread is not needed anymore
+	}
+	p.stopHeartbeat, p.heartbeatDone = stop, done
+	p.heartbeatMu.Unlock()
 	go func() {
+		select {
+		case <-regCtx.Done():
+			if !p.polling() {
+				stop()
+			}
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		defer close(done)
+		failures := 0
 		msgDelayTimer := time.NewTimer(interval)
 		defer msgDelayTimer.Stop()
 		for {
 			msgDelayTimer.Reset(interval)
 			select {
 			case <-ctx.Done():
-				logrus.Error("context canceled")
+				logrus.Infoln("stopped sending heartbeats")
 				return
+			case id := <-p.unknownDelegates():
+				if id == req.ID {
+					logrus.WithField("id", id).Warnln("task server does not know the delegate")
+					p.reregister(ctx, req)
+					failures = 0
+				}
 			case <-msgDelayTimer.C:
 				req.LastHeartbeat = time.Now().UnixMilli()
 				heartbeatCtx, cancelFn := context.WithTimeout(ctx, heartbeatTimeout)
-				err := p.Client.Heartbeat(heartbeatCtx, req)
+				resp, err := p.Client.Heartbeat(heartbeatCtx, req)
+				cancelFn()
 				if err != nil {
 					logrus.WithError(err).Errorf("could not send heartbeat")
+					p.onError(err)
+					p.lifecycle.transition(StateDegraded)
+					failures++
+					if failures >= maxHeartbeatFailures || client.IsNotFound(err) {
+						p.reregister(ctx, req)
+						failures = 0
+					}
+					continue
+				}
+				failures = 0
+				if state, _ := p.lifecycle.current(); state == StateDegraded {
+					p.lifecycle.transition(p.active())
+				}
+				if resp.Resource.Status == client.DelegateDeleted {
+					logrus.WithField("id", req.ID).Warnln("delegate is not registered with the server anymore")
+					p.reregister(ctx, req)
+					continue
+				}
+				for _, taskID := range resp.Resource.AbortedTaskIDs {
+					if p.Abort(taskID) {
+						logrus.WithField("task_id", taskID).Infoln("aborting task on request of the task server")
+					}
 				}
-				cancelFn()
 			}
 		}
 	}()
 }
 
+// stopHeartbeats stops the heartbeat thread of the last registration and waits for it to exit
+func (p *Poller) stopHeartbeats() {
+	p.heartbeatMu.Lock()
+	stop, done := p.stopHeartbeat, p.heartbeatDone
+	p.heartbeatMu.Unlock()
+	if stop == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+// reregister registers the runner again with the server, retrying with a backoff until it
+// succeeds or ctx is done. The new delegate ID is used by the heartbeats and for polling.
+func (p *Poller) reregister(ctx context.Context, req *client.RegisterRequest) {
+	oldID := req.ID
+	logrus.WithField("id", oldID).Infoln("registering the delegate again")
+	if state, _ := p.lifecycle.current(); state != StateDegraded {
+		// a poller whose heartbeats are failing stays degraded until the task server is back
+		p.lifecycle.transition(StateRegistering)
+	}
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = 0 // keep trying until the context is done
+	err := backoff.RetryNotify(func() error {
+		req.ID = ""
+		req.LastHeartbeat = time.Now().UnixMilli()
+		resp, err := p.Client.Register(ctx, req)
+		if err != nil {
+			return err
+		}
+		req.ID = resp.Resource.DelegateID
+		return nil
+	}, backoff.WithContext(b, ctx), func(err error, d time.Duration) {
+		logrus.WithError(err).Warnf("could not register the delegate again, retrying in %s", d)
+	})
+	if err != nil {
+		req.ID = oldID
+		return
+	}
+	p.setDelegateID(req.ID)
+	p.lifecycle.transition(p.active())
+	logrus.WithField("id", req.ID).WithField("old_id", oldID).Info("registered delegate again successfully")
+}
+
+// unknownDelegate asks the heartbeat thread to register the runner again
+// because the task server does not know the delegate ID anymore.
+func (p *Poller) unknownDelegate(id string) {
+	select {
+	case p.unknownDelegates() <- id:
+	default: // already asked
+	}
+}
+
+func (p *Poller) unknownDelegates() chan string {
+	p.unknownOnce.Do(func() {
+		p.unknown = make(chan string, 1)
+	})
+	return p.unknown
+}
+
+// onError reacts to errors of the task server which call for more than a retry.
+// A rejected token is replaced so that the next calls get through.
+func (p *Poller) onError(err error) {
+	if !client.IsUnauthorized(err) {
+		return
+	}
+	if r, ok := p.Client.(client.TokenRefresher); ok {
+		logrus.WithError(err).Warnln("task server rejected the token, refreshing it")
+		r.RefreshToken()
+	}
+}
+
+// unregister tells the task server that the runner is shutting down,
+// so that it does not wait for the heartbeats to time out.
+func (p *Poller) unregister() {
+	p.idMu.RLock()
+	req := &client.RegisterRequest{
+		AccountID:    p.AccountID,
+		DelegateName: p.Name,
+		ID:           p.id,
+		NG:           true,
+		Type:         "DOCKER",
+		HostName:     p.host,
+		IP:           p.ip,
+	}
+	p.idMu.RUnlock()
+	ctx, cancel := context.WithTimeout(context.Background(), unregisterTimeout)
+	defer cancel()
+	if err := p.Client.Unregister(ctx, req); err != nil {
+		logrus.WithError(err).WithField("id", req.ID).Errorln("could not unregister the delegate")
+		return
+	}
+	logrus.WithField("id", req.ID).Infoln("unregistered delegate successfully")
+}
+
+// delegateID returns the ID the runner is currently registered with
+func (p *Poller) delegateID() string {
+	p.idMu.RLock()
+	defer p.idMu.RUnlock()
+	return p.id
+}
+
+func (p *Poller) setDelegateID(id string) {
+	p.idMu.Lock()
+	defer p.idMu.Unlock()
+	p.id = id
+}
+
+// initDelegateID sets the delegate ID unless the runner has already been registered by this poller
+func (p *Poller) initDelegateID(id string) {
+	p.idMu.Lock()
+	defer p.idMu.Unlock()
+	if p.id == "" {
+		p.id = id
+	}
+}
+
+// sleep waits for the given duration. It returns false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
 // Get preferred outbound ip of this machine. It returns a fake IP in case of errors.
 func getOutboundIP() string {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
@@ -0,0 +1,674 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/client/clienttest"
+	"github.com/wings-software/dlite/delegate"
+	"github.com/wings-software/dlite/router"
+	"github.com/wings-software/dlite/task"
+)
+
+const testTaskType = "TEST_TASK"
+
+// newTestPoller returns a poller which routes the test task type to h
+func newTestPoller(c client.Client, h http.HandlerFunc) *Poller {
+	r := router.NewRouter(map[string]task.Handler{testTaskType: h})
+	return New("account", "secret", "runner", nil, c, r)
+}
+
+// poll runs Poll in the background and returns a channel which is closed once it returns
+func poll(t *testing.T, ctx context.Context, p *Poller, n int) <-chan struct{} {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := p.Poll(ctx, n, clienttest.DefaultDelegateID, 10*time.Millisecond); err != nil {
+			t.Errorf("poll failed: %s", err)
+		}
+	}()
+	return done
+}
+
+// waitFor fails the test if ch is not closed within a few seconds
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+// eventually fails the test if cond does not hold within a few seconds
+func eventually(t *testing.T, cond func() bool, what string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// blocking returns a handler which blocks until release is closed, and a channel
+// which is closed once the handler has been called
+func blocking(release <-chan struct{}) (http.HandlerFunc, <-chan struct{}) {
+	started := make(chan struct{})
+	var once sync.Once
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-release
+	}, started
+}
+
+// setHeartbeatInterval shortens the heartbeat interval for the duration of a test
+func setHeartbeatInterval(t *testing.T, d time.Duration) {
+	old := hearbeatInterval
+	hearbeatInterval = d
+	t.Cleanup(func() { hearbeatInterval = old })
+}
+
+func TestPollStopsAfterShutdown(t *testing.T) {
+	// Poll returning races with the drain thread, so give the race a few chances
+	for i := 0; i < 20; i++ {
+		c := clienttest.New()
+		c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+		p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := poll(t, ctx, p, 1)
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := c.WaitForStatuses(waitCtx, 1); err != nil {
+			t.Fatal(err)
+		}
+		waitCancel()
+		cancel()
+		waitFor(t, done, "poll to return")
+		if state := p.State(); state != StateStopped {
+			t.Fatalf("got state %s after poll returned, want %s", state, StateStopped)
+		}
+	}
+}
+
+func TestHeartbeatsContinueWhileDraining(t *testing.T) {
+	setHeartbeatInterval(t, 10*time.Millisecond)
+	c := clienttest.New()
+	c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	release := make(chan struct{})
+	h, started := blocking(release)
+	p := newTestPoller(c, h)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := p.Register(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := poll(t, ctx, p, 1)
+	waitFor(t, started, "the task to start")
+
+	cancel()
+	n := len(c.Heartbeats())
+	eventually(t, func() bool { return len(c.Heartbeats()) >= n+3 }, "heartbeats while draining")
+	close(release)
+	waitFor(t, done, "poll to return")
+
+	n = len(c.Heartbeats())
+	time.Sleep(50 * time.Millisecond)
+	if got := len(c.Heartbeats()); got != n {
+		t.Errorf("got %d heartbeats after poll returned, want %d", got, n)
+	}
+}
+
+func TestNoAcquireAfterShutdown(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	pollCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ev := client.TaskEvent{TaskID: "task-1", TaskType: testTaskType}
+	if err := p.execute(context.Background(), pollCtx, clienttest.DefaultDelegateID, ev, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.Pending()); got != 1 {
+		t.Errorf("got %d pending tasks, want the task to be left to other delegates", got)
+	}
+	if got := len(c.RunnerStatuses()); got != 0 {
+		t.Errorf("got %d statuses, want none", got)
+	}
+}
+
+func TestUnregisterAfterHeartbeatsStop(t *testing.T) {
+	setHeartbeatInterval(t, 5*time.Millisecond)
+	c := clienttest.New()
+	c.SetLatency(clienttest.Heartbeat, 2*time.Millisecond)
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	// The context of Register outlives the one of Poll
+	if _, err := p.Register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 1)
+	eventually(t, func() bool { return len(c.Heartbeats()) >= 3 }, "heartbeats")
+	cancel()
+	waitFor(t, done, "poll to return")
+
+	if got := len(c.Unregistrations()); got != 1 {
+		t.Fatalf("got %d unregistrations, want 1", got)
+	}
+	n := len(c.Heartbeats())
+	time.Sleep(50 * time.Millisecond)
+	if got := len(c.Heartbeats()); got != n {
+		t.Errorf("got %d heartbeats after unregistering, want %d", got, n)
+	}
+	if got := len(c.Registrations()); got != 1 {
+		t.Errorf("got %d registrations, want the runner to stay unregistered", got)
+	}
+}
+
+// runTask runs a single task through Poll and returns the status sent for it
+func runTask(t *testing.T, p *Poller, c *clienttest.Client, task *client.Task) clienttest.RunnerStatus {
+	t.Helper()
+	c.Enqueue(task)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 1)
+	defer waitFor(t, done, "poll to return")
+	defer cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if err := c.WaitForStatuses(waitCtx, 1); err != nil {
+		t.Fatal(err)
+	}
+	return c.RunnerStatuses()[0]
+}
+
+func TestDrainWaitsForTasks(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	release := make(chan struct{})
+	h, started := blocking(release)
+	p := newTestPoller(c, h)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 1)
+	waitFor(t, started, "the task to start")
+
+	cancel()
+	eventually(t, func() bool { return p.State() == StateDraining }, "the poller to drain")
+	select {
+	case <-done:
+		t.Fatal("poll returned before the in-flight task completed")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	waitFor(t, done, "poll to return")
+
+	statuses := c.RunnerStatuses()
+	if len(statuses) != 1 || statuses[0].Response.Code != client.Success {
+		t.Errorf("got statuses %+v, want the drained task to succeed", statuses)
+	}
+}
+
+func TestDrainTimeoutCancelsTasks(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	started := make(chan struct{})
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	p.SetDrainTimeout(20 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 1)
+	waitFor(t, started, "the task to start")
+
+	cancel()
+	waitFor(t, done, "poll to return")
+	statuses := c.RunnerStatuses()
+	if len(statuses) != 1 || statuses[0].Response.Code != client.Failure {
+		t.Errorf("got statuses %+v, want the cancelled task to fail", statuses)
+	}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	c := clienttest.New()
+	release := make(chan struct{})
+	defer close(release)
+	h, _ := blocking(release)
+	p := newTestPoller(c, h)
+	status := runTask(t, p, c, &client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true, Timeout: 20})
+	if status.Response.Code != client.Timeout {
+		t.Errorf("got code %s, want %s", status.Response.Code, client.Timeout)
+	}
+}
+
+func TestAbortOnHeartbeat(t *testing.T) {
+	setHeartbeatInterval(t, 10*time.Millisecond)
+	c := clienttest.New()
+	release := make(chan struct{})
+	defer close(release)
+	h, started := blocking(release)
+	p := newTestPoller(c, h)
+	if _, err := p.Register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.stopHeartbeats()
+	go func() {
+		<-started
+		c.Abort("task-1")
+	}()
+	status := runTask(t, p, c, &client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	if status.Response.Code != client.Aborted {
+		t.Errorf("got code %s, want %s", status.Response.Code, client.Aborted)
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	c := clienttest.New()
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	status := runTask(t, p, c, &client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+	if status.Response.Code != client.Failure {
+		t.Errorf("got code %s, want %s", status.Response.Code, client.Failure)
+	}
+	if !strings.Contains(status.Response.Error, "boom") {
+		t.Errorf("got error %q, want it to mention the panic", status.Response.Error)
+	}
+}
+
+func TestResponseCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+		want   client.ResponseCode
+	}{
+		{name: "no status", want: client.Success},
+		{name: "ok", status: http.StatusOK, want: client.Success},
+		{name: "no content", status: http.StatusNoContent, want: client.Success},
+		{name: "bad request", status: http.StatusBadRequest, want: client.Failure},
+		{name: "request timeout", status: http.StatusRequestTimeout, want: client.Timeout},
+		{name: "gateway timeout", status: http.StatusGatewayTimeout, want: client.Timeout},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, want: client.RetryOnOtherDelegate},
+		{name: "internal error without header", status: http.StatusInternalServerError, want: client.Failure},
+		{name: "header on success", status: http.StatusOK, header: string(client.Aborted), want: client.Aborted},
+		{name: "header on error", status: http.StatusInternalServerError, header: string(client.RetryOnOtherDelegate), want: client.RetryOnOtherDelegate},
+		{name: "header without status", header: string(client.Timeout), want: client.Timeout},
+		{name: "success header on error", status: http.StatusBadGateway, header: string(client.Success), want: client.Success},
+		{name: "invalid header", status: http.StatusOK, header: "DONE", want: client.Success},
+		{name: "invalid header on error", status: http.StatusInternalServerError, header: "DONE", want: client.Failure},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewResponseWriter()
+			if test.header != "" {
+				w.Header().Set(task.ResponseCodeHeader, test.header)
+			}
+			if test.status != 0 {
+				w.WriteHeader(test.status)
+			}
+			if got := responseCode(w); got != test.want {
+				t.Errorf("got code %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLegacyResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantCode client.ResponseCode
+		wantData string
+	}{
+		{
+			name: "success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"result":"done"}`)) //nolint:errcheck
+			},
+			wantCode: client.Success,
+			wantData: `{"result":"done"}`,
+		},
+		{
+			name: "failure with data",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"reason":"bad input"}`)) //nolint:errcheck
+			},
+			wantCode: client.Failure,
+			wantData: `{"reason":"bad input"}`,
+		},
+		{
+			name: "failure without data",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantCode: client.Failure,
+			wantData: `{"error":"Failed executing task with error code 500"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := clienttest.New()
+			c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType})
+			p := newTestPoller(c, test.handler)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := poll(t, ctx, p, 1)
+			defer waitFor(t, done, "poll to return")
+			defer cancel()
+			waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer waitCancel()
+			if err := c.WaitForStatuses(waitCtx, 1); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(c.RunnerStatuses()); n != 0 {
+				t.Errorf("got %d runner statuses for a legacy task, want none", n)
+			}
+			status := c.Statuses()[0]
+			if status.TaskID != "task-1" || status.DelegateID != clienttest.DefaultDelegateID {
+				t.Errorf("got status of task %s from %s, want task-1 from %s", status.TaskID, status.DelegateID, clienttest.DefaultDelegateID)
+			}
+			resp := status.Response
+			if resp.ID != "task-1" || resp.Type != testTaskType {
+				t.Errorf("got response for task %s of type %s, want task-1 of type %s", resp.ID, resp.Type, testTaskType)
+			}
+			if resp.Code != string(test.wantCode) {
+				t.Errorf("got code %s, want %s", resp.Code, test.wantCode)
+			}
+			if string(resp.Data) != test.wantData {
+				t.Errorf("got data %s, want %s", resp.Data, test.wantData)
+			}
+		})
+	}
+}
+
+func TestReregisterAfterHeartbeatFailures(t *testing.T) {
+	setHeartbeatInterval(t, 5*time.Millisecond)
+	c := clienttest.New()
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	if _, err := p.Register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.stopHeartbeats()
+	c.SetDelegateID("delegate-2")
+	c.SetError(clienttest.Heartbeat, errors.New("heartbeat failed"))
+	eventually(t, func() bool { return len(c.Registrations()) >= 2 }, "the runner to register again")
+	c.SetError(clienttest.Heartbeat, nil)
+	eventually(t, func() bool {
+		heartbeats := c.Heartbeats()
+		return len(heartbeats) > 0 && heartbeats[len(heartbeats)-1].ID == "delegate-2"
+	}, "heartbeats with the new delegate ID")
+	if got := p.delegateID(); got != "delegate-2" {
+		t.Errorf("got delegate ID %s, want delegate-2", got)
+	}
+}
+
+func TestDegradedDuringManagerOutage(t *testing.T) {
+	setHeartbeatInterval(t, 5*time.Millisecond)
+	c := clienttest.New()
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	if _, err := p.Register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.stopHeartbeats()
+	changes, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+	outage := errors.New("manager is down")
+	c.SetError(clienttest.Heartbeat, outage)
+	c.SetError(clienttest.Register, outage)
+	eventually(t, func() bool { return p.State() == StateDegraded }, "the poller to degrade")
+	// long enough for the heartbeat thread to give up on heartbeats and register again
+	time.Sleep(time.Duration(maxHeartbeatFailures+10) * hearbeatInterval)
+	if got := p.State(); got != StateDegraded {
+		t.Errorf("got state %s during the outage, want %s", got, StateDegraded)
+	}
+	c.SetError(clienttest.Heartbeat, nil)
+	c.SetError(clienttest.Register, nil)
+	eventually(t, func() bool { return p.State() == StateRegistered }, "the poller to recover")
+	unsubscribe()
+	for change := range changes {
+		if change.To == StateRegistering {
+			t.Errorf("poller went from %s to %s during the outage", change.From, change.To)
+		}
+	}
+}
+
+func TestReregisterWhenDelegateUnknown(t *testing.T) {
+	setHeartbeatInterval(t, 5*time.Millisecond)
+	c := clienttest.New()
+	// only an unknown delegate can make the runner register again
+	old := maxHeartbeatFailures
+	maxHeartbeatFailures = 1000
+	t.Cleanup(func() { maxHeartbeatFailures = old })
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	if _, err := p.Register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.stopHeartbeats()
+	c.SetDelegateID("delegate-2")
+	c.SetError(clienttest.Heartbeat, clienttest.ErrNotFound)
+	eventually(t, func() bool { return len(c.Registrations()) >= 2 }, "the runner to register again")
+}
+
+func TestDropTaskWhichNoLongerExists(t *testing.T) {
+	c := clienttest.New()
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	ev := client.TaskEvent{TaskID: "task-1", TaskType: testTaskType}
+	if err := p.execute(context.Background(), context.Background(), clienttest.DefaultDelegateID, ev, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, rejected := p.rejectedTasks().Get("task-1"); !rejected {
+		t.Error("task which no longer exists is not dropped")
+	}
+}
+
+func TestPriorityAppliesToCapacity(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(
+		&client.Task{ID: "low", Type: "LOW", RunnerResponse: true},
+		&client.Task{ID: "high", Type: "HIGH", RunnerResponse: true},
+	)
+	var mu sync.Mutex
+	var order []string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var task client.Task
+		json.NewDecoder(r.Body).Decode(&task) //nolint:errcheck
+		mu.Lock()
+		order = append(order, task.ID)
+		mu.Unlock()
+	})
+	r := router.NewRouter(map[string]task.Handler{"LOW": h, "HIGH": h})
+	p := New("account", "secret", "runner", nil, c, r)
+	p.SetPriority(ByTaskType("HIGH", "LOW"), time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 1)
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if err := c.WaitForStatuses(waitCtx, 2); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	waitFor(t, done, "poll to return")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 2 || order[0] != "high" {
+		t.Errorf("got tasks executed in order %v, want the high priority task first", order)
+	}
+}
+
+func TestPollingResumesAfterCircuitCloses(t *testing.T) {
+	var down int32 = 1
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"delegateTaskEvents": []}`)) //nolint:errcheck
+	}))
+	defer server.Close()
+	c := delegate.NewFromToken(server.URL, "account", "token", false, "")
+	c.Breaker = delegate.NewCircuitBreaker(2, 50*time.Millisecond)
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 1)
+	defer waitFor(t, done, "poll to return")
+	defer cancel()
+
+	eventually(t, func() bool { return c.Breaker.State(client.MethodGetTaskEvents) == delegate.BreakerOpen }, "the circuit to open")
+	atomic.StoreInt32(&down, 0)
+	recovered := atomic.LoadInt32(&polls)
+	eventually(t, func() bool { return atomic.LoadInt32(&polls) > recovered+2 }, "polling to resume")
+	if state := c.Breaker.State(client.MethodGetTaskEvents); state != delegate.BreakerClosed {
+		t.Errorf("got circuit %s, want it closed once the manager recovered", state)
+	}
+}
+
+// usedSlots returns the executor slots in use by the running Poll call
+func usedSlots(p *Poller) int {
+	p.poolMu.Lock()
+	pl := p.pool
+	p.poolMu.Unlock()
+	if pl == nil {
+		return -1
+	}
+	pl.slots.mu.Lock()
+	defer pl.slots.mu.Unlock()
+	return pl.slots.used
+}
+
+func TestSetParallelismWhileRunning(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		from, to int
+	}{
+		{name: "shrink", from: 4, to: 2},
+		{name: "grow", from: 2, to: 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := clienttest.New()
+			c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true})
+			release := make(chan struct{})
+			h, started := blocking(release)
+			p := newTestPoller(c, h)
+			// a task takes up all the executors, however many there are
+			p.SetLimits(map[string]Limit{testTaskType: {Weight: 4}})
+			ctx, cancel := context.WithCancel(context.Background())
+			done := poll(t, ctx, p, test.from)
+			defer waitFor(t, done, "poll to return")
+			defer cancel()
+			waitFor(t, started, "the task to start")
+
+			if err := p.SetParallelism(ctx, test.to); err != nil {
+				t.Fatal(err)
+			}
+			capacities := c.Capacities()
+			if len(capacities) == 0 || capacities[len(capacities)-1].Capacity.MaxBuilds != test.to {
+				t.Errorf("got registered capacities %+v, want the last one to be %d", capacities, test.to)
+			}
+			close(release)
+			eventually(t, func() bool { return len(c.RunnerStatuses()) == 1 }, "the task to complete")
+			eventually(t, func() bool { return usedSlots(p) == 0 }, "the slots of the task to be released")
+
+			c.Enqueue(&client.Task{ID: "task-2", Type: testTaskType, RunnerResponse: true})
+			eventually(t, func() bool { return len(c.RunnerStatuses()) == 2 }, "a task to run after resizing")
+		})
+	}
+}
+
+// tracking returns a handler which blocks until release is closed, and a function
+// returning the IDs of the tasks it has started
+func tracking(release <-chan struct{}) (http.HandlerFunc, func() []string) {
+	var mu sync.Mutex
+	var started []string
+	h := func(w http.ResponseWriter, r *http.Request) {
+		var task client.Task
+		json.NewDecoder(r.Body).Decode(&task) //nolint:errcheck
+		mu.Lock()
+		started = append(started, task.ID)
+		mu.Unlock()
+		<-release
+	}
+	return h, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), started...)
+	}
+}
+
+// pendingIDs returns the IDs of the tasks which have not been acquired
+func pendingIDs(c *clienttest.Client) []string {
+	var ids []string
+	for _, task := range c.Pending() {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+// runLimited polls tasks of the types A and B with n executors and the given limits. It returns
+// the tasks started and the tasks left unacquired while the first tasks are running, and waits
+// for all the tasks to complete once they are released.
+func runLimited(t *testing.T, n int, limits map[string]Limit, tasks ...*client.Task) (started, pending []string) {
+	t.Helper()
+	c := clienttest.New()
+	c.Enqueue(tasks...)
+	release := make(chan struct{})
+	h, startedIDs := tracking(release)
+	p := New("account", "secret", "runner", nil, c, router.NewRouter(map[string]task.Handler{"A": h, "B": h}))
+	p.SetLimits(limits)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, n)
+	defer waitFor(t, done, "poll to return")
+	defer cancel()
+
+	eventually(t, func() bool { return len(startedIDs())+len(pendingIDs(c)) == len(tasks) }, "the tasks to be acquired")
+	// give the poller a few more polls to acquire tasks it should not
+	time.Sleep(50 * time.Millisecond)
+	started, pending = startedIDs(), pendingIDs(c)
+	close(release)
+	eventually(t, func() bool { return len(c.RunnerStatuses()) == len(tasks) }, "all the tasks to complete")
+	return started, pending
+}
+
+func TestMaxConcurrentLeavesTasksOfTheTypeUnacquired(t *testing.T) {
+	started, pending := runLimited(t, 3, map[string]Limit{"A": {MaxConcurrent: 1}},
+		&client.Task{ID: "a-1", Type: "A", RunnerResponse: true},
+		&client.Task{ID: "a-2", Type: "A", RunnerResponse: true},
+		&client.Task{ID: "b-1", Type: "B", RunnerResponse: true},
+	)
+	sort.Strings(started)
+	if len(started) != 2 || started[0] != "a-1" || started[1] != "b-1" {
+		t.Errorf("got tasks %v started, want a-1 and b-1", started)
+	}
+	if len(pending) != 1 || pending[0] != "a-2" {
+		t.Errorf("got tasks %v left unacquired, want a-2", pending)
+	}
+}
+
+func TestWeightTakesUpExecutors(t *testing.T) {
+	started, pending := runLimited(t, 3, map[string]Limit{"A": {Weight: 2}},
+		&client.Task{ID: "a-1", Type: "A", RunnerResponse: true},
+		&client.Task{ID: "a-2", Type: "A", RunnerResponse: true},
+		&client.Task{ID: "b-1", Type: "B", RunnerResponse: true},
+	)
+	sort.Strings(started)
+	if len(started) != 2 || started[0] != "a-1" || started[1] != "b-1" {
+		t.Errorf("got tasks %v started, want a-1 and b-1 to take up the 3 executors", started)
+	}
+	if len(pending) != 1 || pending[0] != "a-2" {
+		t.Errorf("got tasks %v left unacquired, want a-2", pending)
+	}
+}
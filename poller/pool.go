@@ -0,0 +1,88 @@
+package poller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wings-software/dlite/client"
+)
+
+// pool runs the executors which pick up task events and execute them.
+// The number of executors can be changed while the pool is running.
+// Once an event has been executed, the slots reserved for it are released.
+type pool struct {
+	ctx    context.Context
+	events *queue
+	slots  *capacity
+	work   func(ev *client.TaskEvent, i int)
+
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+	next int             // index of the next executor to start
+	quit []chan struct{} // one channel per running executor, in the order they were started
+}
+
+func newPool(ctx context.Context, events *queue, slots *capacity, work func(*client.TaskEvent, int)) *pool {
+	return &pool{ctx: ctx, events: events, slots: slots, work: work}
+}
+
+// resize starts or retires executors until n of them are running. A retired executor
+// finishes its current task before it stops. It returns false if the pool is shutting down.
+func (p *pool) resize(n int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ctx.Err() != nil {
+		return false
+	}
+	p.slots.resize(n)
+	for len(p.quit) < n {
+		p.start()
+	}
+	for len(p.quit) > n {
+		last := len(p.quit) - 1
+		close(p.quit[last])
+		p.quit = p.quit[:last]
+	}
+	return true
+}
+
+// wait blocks until all the executors have stopped
+func (p *pool) wait() {
+	p.wg.Wait()
+}
+
+// start starts a new executor. It must be called with the lock held.
+func (p *pool) start() {
+	i := p.next
+	p.next++
+	quit := make(chan struct{})
+	p.quit = append(p.quit, quit)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			// Do not pick up any new task once a shutdown has been requested or the executor is retired
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-quit:
+				logrus.Infof("[Thread %d]: executor retired", i)
+				return
+			default:
+			}
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-quit:
+				logrus.Infof("[Thread %d]: executor retired", i)
+				return
+			case <-p.events.ready:
+				if ev, weight, ok := p.events.pop(); ok {
+					p.work(ev, i)
+					p.slots.release(ev.TaskType, weight)
+				}
+			}
+		}
+	}()
+}
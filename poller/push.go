@@ -0,0 +1,231 @@
+package poller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wings-software/dlite/client"
+)
+
+var (
+	// Time after which a task event requeued by the poller is handed out again
+	requeueDelay = 5 * time.Second
+	// Number of times a task event is requeued before it is dropped
+	maxRequeues = 60
+)
+
+// Requeuer is an EventSource which can hand out task events again. Task servers which push
+// task events deliver every event once, unlike polled task servers which keep returning
+// pending events. The poller requeues the events it does not take on, e.g. because all its
+// executors are busy, so that they are not lost to the runner.
+type Requeuer interface {
+	EventSource
+	// Requeue hands out the task events again in a later call to Next
+	Requeue(evs []*client.TaskEvent)
+}
+
+// OpenFunc opens a stream of task events for a delegate. The returned recv function blocks
+// until the next task event arrives and returns an error once the stream is broken. The
+// stream must be closed once ctx is done.
+type OpenFunc func(ctx context.Context, delegateID string) (recv func() (*client.TaskEvent, error), err error)
+
+// PushSource receives task events pushed by the task server over a stream opened with Open.
+// The stream is opened on the first call to Next and opened again after it breaks. It lives
+// as long as the context of the call to Next which opened it, or until Close is called.
+type PushSource struct {
+	Open OpenFunc
+	// RequeueDelay is the time after which a requeued task event is handed out again
+	RequeueDelay time.Duration
+	// MaxRequeues is the number of times a task event is requeued before it is dropped
+	MaxRequeues int
+
+	mu         sync.Mutex
+	delegateID string // delegate the open stream belongs to
+	cancel     context.CancelFunc
+	stream     int // generation of the open stream, so that a stale receiver does not report
+	pending    []pushed
+	handed     map[string]int // requeues of the task events handed out by the last call to Next
+	err        error          // error which broke the stream, reported by the next call to Next
+	changed    chan struct{}  // closed and replaced whenever pending or err change
+}
+
+// pushed is a task event waiting to be handed out
+type pushed struct {
+	ev       *client.TaskEvent
+	due      time.Time // time from which the event can be handed out
+	requeues int
+}
+
+var _ Requeuer = (*PushSource)(nil)
+
+func NewPushSource(open OpenFunc) *PushSource {
+	return &PushSource{Open: open, RequeueDelay: requeueDelay, MaxRequeues: maxRequeues}
+}
+
+// Next waits for the task server to push task events and returns all the events received
+// so far, along with the requeued events which are due.
+func (s *PushSource) Next(ctx context.Context, delegateID string) ([]*client.TaskEvent, error) {
+	if err := s.connect(ctx, delegateID); err != nil {
+		return nil, err
+	}
+	for {
+		s.mu.Lock()
+		evs, wait := s.due(time.Now())
+		err := s.err
+		changed := s.changed
+		if len(evs) == 0 {
+			s.err = nil
+		}
+		s.mu.Unlock()
+		if len(evs) > 0 {
+			return evs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !s.wait(ctx, changed, wait) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Requeue hands out the task events again once the requeue delay is over, unless they
+// have been requeued too many times already
+func (s *PushSource) Requeue(evs []*client.TaskEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := time.Now().Add(s.RequeueDelay)
+	for _, ev := range evs {
+		requeues := s.handed[ev.TaskID] + 1
+		if s.MaxRequeues > 0 && requeues > s.MaxRequeues {
+			logrus.WithField("task_id", ev.TaskID).Warnln("task event has been requeued too many times, dropping it")
+			continue
+		}
+		s.pending = append(s.pending, pushed{ev: ev, due: due, requeues: requeues})
+	}
+	s.notify()
+}
+
+// Close closes the stream
+func (s *PushSource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnect()
+}
+
+// connect opens the stream for the delegate unless it is already open
+func (s *PushSource) connect(ctx context.Context, delegateID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	if s.cancel != nil && s.delegateID == delegateID {
+		return nil
+	}
+	if s.cancel != nil {
+		// the delegate has registered again, the stream of the old delegate ID is of no use
+		s.disconnect()
+	}
+	if s.err != nil {
+		// the stream broke and the error has not been reported yet
+		return nil
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	recv, err := s.Open(streamCtx, delegateID)
+	if err != nil {
+		cancel()
+		return err
+	}
+	logrus.WithField("id", delegateID).Infoln("opened task event stream")
+	s.delegateID = delegateID
+	s.cancel = cancel
+	s.stream++
+	go s.receive(streamCtx, s.stream, recv)
+	return nil
+}
+
+// receive queues the task events of a stream until it breaks
+func (s *PushSource) receive(ctx context.Context, stream int, recv func() (*client.TaskEvent, error)) {
+	for {
+		ev, err := recv()
+		s.mu.Lock()
+		if s.stream != stream || ctx.Err() != nil {
+			// the stream has been closed or replaced
+			s.mu.Unlock()
+			return
+		}
+		if err != nil {
+			s.err = err
+			s.disconnect()
+			s.notify()
+			s.mu.Unlock()
+			return
+		}
+		s.pending = append(s.pending, pushed{ev: ev})
+		s.notify()
+		s.mu.Unlock()
+	}
+}
+
+// due removes the task events which can be handed out from the pending ones and returns
+// them, along with the time to wait for the next requeued event. It must be called with
+// the lock held.
+func (s *PushSource) due(now time.Time) ([]*client.TaskEvent, time.Duration) {
+	var evs []*client.TaskEvent
+	var wait time.Duration
+	handed := map[string]int{}
+	pending := s.pending[:0]
+	for _, p := range s.pending {
+		if p.due.After(now) {
+			if d := p.due.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			pending = append(pending, p)
+			continue
+		}
+		evs = append(evs, p.ev)
+		handed[p.ev.TaskID] = p.requeues
+	}
+	s.pending = pending
+	if len(evs) > 0 {
+		s.handed = handed
+	}
+	return evs, wait
+}
+
+// wait blocks until the pending events change, the wait is over or ctx is done.
+// A zero wait waits for the pending events to change. It returns false if ctx is done.
+func (s *PushSource) wait(ctx context.Context, changed <-chan struct{}, wait time.Duration) bool {
+	var timeout <-chan time.Time
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-changed:
+	case <-timeout:
+	}
+	return true
+}
+
+// disconnect closes the open stream. It must be called with the lock held.
+func (s *PushSource) disconnect() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// notify wakes up the callers waiting for task events. It must be called with the lock held.
+func (s *PushSource) notify() {
+	if s.changed != nil {
+		close(s.changed)
+	}
+	s.changed = make(chan struct{})
+}
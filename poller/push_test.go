@@ -0,0 +1,134 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/delegate"
+	"github.com/wings-software/dlite/simulator"
+)
+
+func TestPushSourceRequeue(t *testing.T) {
+	events := make(chan *client.TaskEvent, 1)
+	s := NewPushSource(func(ctx context.Context, delegateID string) (func() (*client.TaskEvent, error), error) {
+		return func() (*client.TaskEvent, error) {
+			select {
+			case ev := <-events:
+				return ev, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}, nil
+	})
+	s.RequeueDelay = 10 * time.Millisecond
+	s.MaxRequeues = 2
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events <- &client.TaskEvent{TaskID: "task-1"}
+	for i := 0; i <= s.MaxRequeues; i++ {
+		evs, err := s.Next(ctx, "delegate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(evs) != 1 || evs[0].TaskID != "task-1" {
+			t.Fatalf("got events %v on delivery %d, want task-1", evs, i)
+		}
+		s.Requeue(evs)
+	}
+
+	// the event has been requeued too many times, so only new events are handed out
+	events <- &client.TaskEvent{TaskID: "task-2"}
+	evs, err := s.Next(ctx, "delegate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].TaskID != "task-2" {
+		t.Errorf("got events %v, want only task-2", evs)
+	}
+}
+
+func TestPushSourceReportsBrokenStream(t *testing.T) {
+	opened := 0
+	broken := errors.New("stream broke")
+	s := NewPushSource(func(ctx context.Context, delegateID string) (func() (*client.TaskEvent, error), error) {
+		opened++
+		return func() (*client.TaskEvent, error) { return nil, broken }, nil
+	})
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.Next(ctx, "delegate"); !errors.Is(err, broken) {
+		t.Fatalf("got error %v, want %v", err, broken)
+	}
+	if _, err := s.Next(ctx, "delegate"); !errors.Is(err, broken) {
+		t.Fatalf("got error %v, want %v", err, broken)
+	}
+	if opened != 2 {
+		t.Errorf("stream was opened %d times, want it opened again after it broke", opened)
+	}
+}
+
+// runSimulated runs n tasks on a poller with a single executor against the simulator,
+// with task events delivered by the source returned by newSource
+func runSimulated(t *testing.T, n int, newSource func(c *delegate.HTTPClient) EventSource) {
+	t.Helper()
+	old := requeueDelay
+	requeueDelay = 10 * time.Millisecond
+	t.Cleanup(func() { requeueDelay = old })
+	sim := simulator.New()
+	server := httptest.NewServer(sim)
+	defer server.Close()
+	c := delegate.NewFromToken(server.URL, "account", "token", false, "")
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond) // keep the executor busy while the other events arrive
+	})
+	p.SetSource(newSource(c))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	info, err := p.Register(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		sim.Enqueue(client.Task{Type: testTaskType, RunnerResponse: true})
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Poll(ctx, 1, info.ID, 10*time.Millisecond) //nolint:errcheck
+	}()
+	eventually(t, func() bool {
+		for _, task := range sim.Tasks() {
+			if task.RunnerResponse == nil {
+				return false
+			}
+		}
+		return true
+	}, "all the tasks to complete")
+	cancel()
+	waitFor(t, done, "poll to return")
+}
+
+func TestStreamSourceWithSimulator(t *testing.T) {
+	runSimulated(t, 3, func(c *delegate.HTTPClient) EventSource {
+		return NewStreamSource(http.DefaultClient, func(ctx context.Context, id string) (*http.Request, error) {
+			return c.NewRequest(ctx, "GET", "/api/agent/delegates/"+id+"/task-events/stream", nil)
+		})
+	})
+}
+
+func TestLongPollSourceWithSimulator(t *testing.T) {
+	runSimulated(t, 3, func(c *delegate.HTTPClient) EventSource {
+		return NewLongPollSource(http.DefaultClient, func(ctx context.Context, id string) (*http.Request, error) {
+			return c.NewRequest(ctx, "GET", "/api/agent/delegates/"+id+"/task-events/long-poll?wait=1", nil)
+		})
+	})
+}
@@ -0,0 +1,132 @@
+package poller
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+)
+
+// PriorityFunc assigns a priority to a task event. Events with a higher priority are
+// executed first, events with the same priority in the order they were polled.
+type PriorityFunc func(*client.TaskEvent) int
+
+// SyncFirst executes sync task events before async ones
+func SyncFirst(ev *client.TaskEvent) int {
+	if ev.Sync {
+		return 1
+	}
+	return 0
+}
+
+// ByTaskType executes task events in the order of the given task types. Events of a type
+// which is not in the list come last.
+func ByTaskType(taskTypes ...string) PriorityFunc {
+	priorities := map[string]int{}
+	for i, taskType := range taskTypes {
+		priorities[taskType] = len(taskTypes) - i
+	}
+	return func(ev *client.TaskEvent) int {
+		return priorities[ev.TaskType]
+	}
+}
+
+// queue holds the task events which are waiting for an executor. While an event waits,
+// its priority goes up by one every aging period so that low priority events are not
+// starved by a steady flow of higher priority ones. An event waits from the time it was
+// first polled, including the polls in which there was no capacity left for it.
+type queue struct {
+	priority PriorityFunc
+	aging    time.Duration
+
+	mu    sync.Mutex
+	items []*queued
+	seq   uint64
+	ready chan struct{} // signalled whenever the queue might have an event to pop
+}
+
+type queued struct {
+	ev       *client.TaskEvent
+	priority int
+	seen     time.Time // when the event was first polled
+	seq      uint64
+	weight   int // executor slots reserved for the event
+}
+
+func newQueue(priority PriorityFunc, aging time.Duration) *queue {
+	return &queue{priority: priority, aging: aging, ready: make(chan struct{}, 1)}
+}
+
+// push adds a task event which was first polled at seen to the queue, along with
+// the number of executor slots reserved for it
+func (q *queue) push(ev *client.TaskEvent, seen time.Time, weight int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := &queued{ev: ev, seen: seen, seq: q.seq, weight: weight}
+	if q.priority != nil {
+		item.priority = q.priority(ev)
+	}
+	q.seq++
+	q.items = append(q.items, item)
+	q.signal()
+}
+
+// pop removes the task event with the highest effective priority from the queue and
+// returns it along with the number of executor slots reserved for it. It returns false
+// if the queue is empty.
+func (q *queue) pop() (*client.TaskEvent, int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return nil, 0, false
+	}
+	now := time.Now()
+	best := 0
+	for i := 1; i < len(q.items); i++ {
+		a, b := q.effective(q.items[i], now), q.effective(q.items[best], now)
+		if a > b || (a == b && q.items[i].seq < q.items[best].seq) {
+			best = i
+		}
+	}
+	item := q.items[best]
+	q.items = append(q.items[:best], q.items[best+1:]...)
+	// wake up another executor if there is more work left
+	if len(q.items) > 0 {
+		q.signal()
+	}
+	return item.ev, item.weight, true
+}
+
+// effective returns the priority of a queued event including its aging bonus
+func (q *queue) effective(item *queued, now time.Time) int {
+	if q.aging <= 0 {
+		return item.priority
+	}
+	return item.priority + int(now.Sub(item.seen)/q.aging)
+}
+
+// sort orders polled task events by their effective priority, highest first, so that the
+// capacity left goes to the most important ones. Events of the same priority keep the order
+// they were polled in. seen returns the time an event was first polled.
+func (q *queue) sort(evs []*client.TaskEvent, seen func(*client.TaskEvent) time.Time) {
+	if q.priority == nil {
+		return
+	}
+	now := time.Now()
+	priorities := make(map[*client.TaskEvent]int, len(evs))
+	for _, ev := range evs {
+		priorities[ev] = q.effective(&queued{priority: q.priority(ev), seen: seen(ev)}, now)
+	}
+	sort.SliceStable(evs, func(i, j int) bool {
+		return priorities[evs[i]] > priorities[evs[j]]
+	})
+}
+
+// signal notifies a waiting executor without blocking. It must be called with the lock held.
+func (q *queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
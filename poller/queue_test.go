@@ -0,0 +1,41 @@
+package poller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+)
+
+func TestQueueSort(t *testing.T) {
+	now := time.Now()
+	q := newQueue(ByTaskType("high", "low"), time.Minute)
+	low := &client.TaskEvent{TaskID: "low", TaskType: "low"}
+	high := &client.TaskEvent{TaskID: "high", TaskType: "high"}
+	other := &client.TaskEvent{TaskID: "other", TaskType: "other"}
+	old := &client.TaskEvent{TaskID: "old", TaskType: "other"}
+	seen := map[string]time.Time{
+		"low":   now,
+		"high":  now,
+		"other": now,
+		"old":   now.Add(-3 * time.Minute), // aged by three priority levels
+	}
+	evs := []*client.TaskEvent{other, low, old, high}
+	q.sort(evs, func(ev *client.TaskEvent) time.Time { return seen[ev.TaskID] })
+
+	want := []string{"old", "high", "low", "other"}
+	for i, ev := range evs {
+		if ev.TaskID != want[i] {
+			t.Fatalf("got event %s at position %d, want %s", ev.TaskID, i, want[i])
+		}
+	}
+}
+
+func TestQueueSortKeepsPollOrderWithoutPriority(t *testing.T) {
+	q := newQueue(nil, time.Minute)
+	evs := []*client.TaskEvent{{TaskID: "b"}, {TaskID: "a"}}
+	q.sort(evs, func(*client.TaskEvent) time.Time { return time.Now() })
+	if evs[0].TaskID != "b" || evs[1].TaskID != "a" {
+		t.Errorf("got events %s, %s, want them in the order they were polled", evs[0].TaskID, evs[1].TaskID)
+	}
+}
@@ -0,0 +1,277 @@
+package poller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/wings-software/dlite/client"
+)
+
+// EventSource delivers the task events which are pending for a delegate
+type EventSource interface {
+	// Next blocks until the source has task events for the delegate or decides that it
+	// is time to return, and returns the events. It returns early once ctx is done.
+	Next(ctx context.Context, delegateID string) ([]*client.TaskEvent, error)
+}
+
+// FeedbackSource is an EventSource which learns how many of the task events it delivered
+// were taken on by the poller, e.g. to poll faster only when there is work the runner can do.
+type FeedbackSource interface {
+	EventSource
+	// Reserved is called after every Next call which returned task events, with the number
+	// of them that the poller reserved capacity for and is going to try to acquire.
+	Reserved(n int)
+}
+
+// RequestFunc builds the HTTP request used by a source to ask the task server for task events,
+// including any authorization. For the Harness manager, delegate.HTTPClient.NewRequest can be used.
+type RequestFunc func(ctx context.Context, delegateID string) (*http.Request, error)
+
+// PollingSource asks the task server for task events at an interval which adapts to the load.
+// It polls faster right after the poller took on some of the task events it found, and relaxes
+// to the idle interval once no task events have been taken on for a while. Task events which
+// the runner leaves to other delegates, e.g. because they do not match its filter, do not
+// count. Every interval is randomized by the jitter factor so that runners do not poll the
+// task server in lockstep.
+type PollingSource struct {
+	Client   client.Client
+	Interval time.Duration
+	// MinInterval is used right after task events were found. Defaults to Interval.
+	MinInterval time.Duration
+	// IdleInterval is used once no task events were found for IdleAfter. Defaults to Interval.
+	IdleInterval time.Duration
+	IdleAfter    time.Duration
+	// Jitter randomizes each interval by up to this fraction of it, e.g. 0.2 for ±20%.
+	Jitter float64
+
+	found    bool      // whether the poller took on task events of the last poll
+	lastWork time.Time // last time the poller took on task events
+}
+
+var _ FeedbackSource = (*PollingSource)(nil)
+
+// NewPollingSource returns a source which polls every interval, four times as fast
+// after finding task events and three times slower once idle for five minutes.
+func NewPollingSource(c client.Client, interval time.Duration) *PollingSource {
+	return &PollingSource{
+		Client:       c,
+		Interval:     interval,
+		MinInterval:  interval / 4,
+		IdleInterval: interval * 3,
+		IdleAfter:    5 * time.Minute,
+		Jitter:       0.2,
+	}
+}
+
+// Next waits for the poll interval and then queries the task server for task events
+func (s *PollingSource) Next(ctx context.Context, delegateID string) ([]*client.TaskEvent, error) {
+	if !sleep(ctx, s.next()) {
+		return nil, ctx.Err()
+	}
+	taskEventsCtx, cancelFn := context.WithTimeout(ctx, taskEventsTimeout)
+	defer cancelFn()
+	tasks, err := s.Client.GetTaskEvents(taskEventsCtx, delegateID)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil || len(tasks.TaskEvents) == 0 {
+		s.found = false
+		return nil, nil
+	}
+	return tasks.TaskEvents, nil
+}
+
+// Reserved records how many of the polled task events the poller took on
+func (s *PollingSource) Reserved(n int) {
+	s.found = n > 0
+	if s.found {
+		s.lastWork = time.Now()
+	}
+}
+
+// next returns the time to wait before the next poll
+func (s *PollingSource) next() time.Duration {
+	if s.lastWork.IsZero() {
+		s.lastWork = time.Now()
+	}
+	interval := s.Interval
+	switch {
+	case s.found && s.MinInterval > 0:
+		interval = s.MinInterval
+	case s.IdleAfter > 0 && s.IdleInterval > 0 && time.Since(s.lastWork) > s.IdleAfter:
+		interval = s.IdleInterval
+	}
+	return jitter(interval, s.Jitter)
+}
+
+// random is seeded per process so that runners started together do not share a jitter sequence
+var random = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))} //nolint:gosec
+
+// jitter randomizes d by up to the given fraction of it in either direction
+func jitter(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		return d
+	}
+	random.Lock()
+	r := random.Float64()
+	random.Unlock()
+	delta := factor * float64(d)
+	return time.Duration(float64(d) - delta + r*2*delta)
+}
+
+// LongPollSource asks the task server for task events with requests which the server holds
+// open until events are available or its wait time is over. The server responds with a
+// client.TaskEventsResponse, or with 204 No Content if no task events became available.
+type LongPollSource struct {
+	Client  *http.Client
+	Request RequestFunc
+}
+
+func NewLongPollSource(c *http.Client, request RequestFunc) *LongPollSource {
+	return &LongPollSource{Client: c, Request: request}
+}
+
+// Next sends a long poll request and waits for the task server to respond
+func (s *LongPollSource) Next(ctx context.Context, delegateID string) ([]*client.TaskEvent, error) {
+	req, err := s.Request(ctx, delegateID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create long poll request")
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode > 299 {
+		return nil, fmt.Errorf("long poll failed with status %d: %s", res.StatusCode, body)
+	}
+	tasks := &client.TaskEventsResponse{}
+	if err := json.Unmarshal(body, tasks); err != nil {
+		return nil, errors.Wrap(err, "could not decode task events")
+	}
+	return tasks.TaskEvents, nil
+}
+
+// StreamSource receives task events pushed by the task server over a server-sent events
+// stream. The data of every message is a JSON encoded client.TaskEvent. The stream is
+// opened on the first call to Next and opened again after it breaks. Task events which
+// the poller does not take on are requeued, see PushSource.
+type StreamSource struct {
+	Client  *http.Client
+	Request RequestFunc
+
+	pushOnce sync.Once
+	push     *PushSource
+}
+
+var _ Requeuer = (*StreamSource)(nil)
+
+func NewStreamSource(c *http.Client, request RequestFunc) *StreamSource {
+	return &StreamSource{Client: c, Request: request}
+}
+
+// Next waits for the task server to push task events and returns all the events received so far
+func (s *StreamSource) Next(ctx context.Context, delegateID string) ([]*client.TaskEvent, error) {
+	return s.source().Next(ctx, delegateID)
+}
+
+// Requeue hands out task events again in a later call to Next
+func (s *StreamSource) Requeue(evs []*client.TaskEvent) {
+	s.source().Requeue(evs)
+}
+
+// Close closes the stream
+func (s *StreamSource) Close() {
+	s.source().Close()
+}
+
+func (s *StreamSource) source() *PushSource {
+	s.pushOnce.Do(func() {
+		s.push = NewPushSource(s.open)
+	})
+	return s.push
+}
+
+// open opens the stream for the delegate
+func (s *StreamSource) open(ctx context.Context, delegateID string) (func() (*client.TaskEvent, error), error) {
+	req, err := s.Request(ctx, delegateID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create stream request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		res.Body.Close()
+		return nil, fmt.Errorf("could not open stream, status %d: %s", res.StatusCode, body)
+	}
+	return newEventReader(res.Body).next, nil
+}
+
+// eventReader parses the server-sent events of a stream
+type eventReader struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+func newEventReader(body io.ReadCloser) *eventReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &eventReader{body: body, scanner: scanner}
+}
+
+// next returns the next task event of the stream. The body is closed once the stream breaks.
+func (r *eventReader) next() (*client.TaskEvent, error) {
+	var data bytes.Buffer
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			// a blank line ends the message
+			if data.Len() == 0 {
+				continue
+			}
+			ev := &client.TaskEvent{}
+			if err := json.Unmarshal(data.Bytes(), ev); err != nil {
+				logrus.WithError(err).Errorln("could not decode task event from stream")
+				data.Reset()
+				continue
+			}
+			return ev, nil
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+		}
+	}
+	r.body.Close()
+	err := r.scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	return nil, errors.Wrap(err, "task event stream closed")
+}
@@ -0,0 +1,79 @@
+package poller
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/client/clienttest"
+)
+
+func TestPollingSourceAdaptsToReservedEvents(t *testing.T) {
+	c := clienttest.New()
+	c.Enqueue(&client.Task{ID: "task-1", Type: testTaskType})
+	s := NewPollingSource(c, 40*time.Millisecond)
+	s.Jitter = 0
+
+	if _, err := s.Next(context.Background(), clienttest.DefaultDelegateID); err != nil {
+		t.Fatal(err)
+	}
+	s.Reserved(0)
+	if got := s.next(); got != s.Interval {
+		t.Errorf("got interval %s after no events were taken on, want %s", got, s.Interval)
+	}
+	s.Reserved(1)
+	if got := s.next(); got != s.MinInterval {
+		t.Errorf("got interval %s after events were taken on, want %s", got, s.MinInterval)
+	}
+}
+
+// feedbackSource hands out the same task events on every call and records the feedback of the poller
+type feedbackSource struct {
+	evs []*client.TaskEvent
+
+	mu       sync.Mutex
+	reserved []int
+}
+
+func (s *feedbackSource) Next(ctx context.Context, delegateID string) ([]*client.TaskEvent, error) {
+	if !sleep(ctx, 5*time.Millisecond) {
+		return nil, ctx.Err()
+	}
+	return s.evs, nil
+}
+
+func (s *feedbackSource) Reserved(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reserved = append(s.reserved, n)
+}
+
+func (s *feedbackSource) feedback() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.reserved...)
+}
+
+func TestPollReportsReservedEvents(t *testing.T) {
+	c := clienttest.New()
+	p := newTestPoller(c, func(w http.ResponseWriter, r *http.Request) {})
+	source := &feedbackSource{evs: []*client.TaskEvent{
+		{TaskID: "task-1", TaskType: testTaskType},
+		{TaskID: "task-2", TaskType: "OTHER"},
+	}}
+	p.SetSource(source)
+	p.SetFilter(func(ev *client.TaskEvent) bool { return ev.TaskType == testTaskType })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := poll(t, ctx, p, 2)
+	eventually(t, func() bool { return len(source.feedback()) > 0 }, "feedback from the poller")
+	cancel()
+	waitFor(t, done, "poll to return")
+
+	if got := source.feedback()[0]; got != 1 {
+		t.Errorf("got %d reserved events, want only the event matching the filter", got)
+	}
+}
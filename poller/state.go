@@ -0,0 +1,137 @@
+package poller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// State is a stage in the lifecycle of a poller
+type State int
+
+const (
+	// StateIdle is the state of a poller which has not been registered yet
+	StateIdle State = iota
+	// StateRegistering is the state of a poller which is registering with the task server
+	StateRegistering
+	// StateRegistered is the state of a registered poller which is not polling yet
+	StateRegistered
+	// StatePolling is the state of a poller which is polling for and executing tasks
+	StatePolling
+	// StateDegraded is the state of a poller whose heartbeats are failing. It stays degraded
+	// while it registers again, until a heartbeat or the registration succeeds.
+	StateDegraded
+	// StateDraining is the state of a poller which waits for its in-flight tasks to finish
+	StateDraining
+	// StateStopped is the state of a poller which has stopped polling
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateRegistering:
+		return "registering"
+	case StateRegistered:
+		return "registered"
+	case StatePolling:
+		return "polling"
+	case StateDegraded:
+		return "degraded"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// transitions lists the states a poller can move to from each state
+var transitions = map[State][]State{
+	StateIdle:        {StateRegistering, StatePolling, StateStopped},
+	StateRegistering: {StateIdle, StateRegistered, StatePolling, StateDraining, StateStopped},
+	StateRegistered:  {StateRegistering, StatePolling, StateDegraded, StateStopped},
+	StatePolling:     {StateRegistering, StateDegraded, StateDraining, StateStopped},
+	StateDegraded:    {StateRegistering, StateRegistered, StatePolling, StateDraining, StateStopped},
+	StateDraining:    {StateStopped},
+	StateStopped:     {StateRegistering, StatePolling},
+}
+
+// StateChange describes a transition of a poller from one state to another
+type StateChange struct {
+	From State
+	To   State
+	Time time.Time
+}
+
+// lifecycle tracks the state of a poller and notifies its subscribers about changes
+type lifecycle struct {
+	mu    sync.Mutex
+	state State
+	since time.Time
+	subs  map[chan StateChange]struct{}
+}
+
+// current returns the current state of the poller and the time it entered it
+func (l *lifecycle) current() (State, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state, l.since
+}
+
+// transition moves the poller to a new state. It returns false if the
+// transition is not allowed from the current state.
+func (l *lifecycle) transition(to State) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.state == to {
+		return true
+	}
+	if !allowed(l.state, to) {
+		logrus.Debugf("ignoring poller state transition from %s to %s", l.state, to)
+		return false
+	}
+	change := StateChange{From: l.state, To: to, Time: time.Now()}
+	l.state, l.since = to, change.Time
+	logrus.Infof("poller state changed from %s to %s", change.From, change.To)
+	for sub := range l.subs {
+		select {
+		case sub <- change:
+		default:
+			logrus.Warnf("state change subscriber is not keeping up, dropping change to %s", change.To)
+		}
+	}
+	return true
+}
+
+func (l *lifecycle) subscribe() chan StateChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.subs == nil {
+		l.subs = map[chan StateChange]struct{}{}
+	}
+	sub := make(chan StateChange, 16)
+	l.subs[sub] = struct{}{}
+	return sub
+}
+
+func (l *lifecycle) unsubscribe(sub chan StateChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.subs[sub]; ok {
+		delete(l.subs, sub)
+		close(sub)
+	}
+}
+
+func allowed(from, to State) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
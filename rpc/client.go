@@ -0,0 +1,200 @@
+// Package rpc implements client.Client over gRPC. Task events are pushed by the task server
+// over a server-streaming call. The rpctest package provides an in-process reference server.
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wings-software/dlite/client"
+	pb "github.com/wings-software/dlite/rpc/delegatepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TokenSource provides the delegate tokens the calls are authenticated with, e.g. a
+// delegate.TokenCache generating them from the account secret.
+type TokenSource interface {
+	// Get returns the current token
+	Get() (string, error)
+	// Invalidate drops the current token, so that Get returns a new one
+	Invalidate()
+}
+
+// Client talks to the task server over gRPC. Its OpenStream method opens the stream the task
+// server pushes task events over, for a poller.PushSource to hand them out to the poller.
+type Client struct {
+	AccountTokenCache TokenSource
+	Token             string
+
+	manager pb.ManagerClient
+}
+
+var (
+	_ client.Client         = (*Client)(nil)
+	_ client.TokenRefresher = (*Client)(nil)
+)
+
+// New returns a client which authenticates with the tokens of tokens
+func New(cc grpc.ClientConnInterface, tokens TokenSource) *Client {
+	return &Client{manager: pb.NewManagerClient(cc), AccountTokenCache: tokens}
+}
+
+// NewFromToken returns a client which authenticates with a fixed token
+func NewFromToken(cc grpc.ClientConnInterface, token string) *Client {
+	return &Client{manager: pb.NewManagerClient(cc), Token: token}
+}
+
+// Register registers the runner with the task server
+func (c *Client) Register(ctx context.Context, r *client.RegisterRequest) (*client.RegisterResponse, error) {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.manager.Register(ctx, ToRegisterRequest(r))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &client.RegisterResponse{Resource: client.RegistrationData{DelegateID: resp.GetDelegateId()}}, nil
+}
+
+// Unregister tells the task server that the runner is shutting down
+func (c *Client) Unregister(ctx context.Context, r *client.RegisterRequest) error {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.manager.Unregister(ctx, ToRegisterRequest(r))
+	return statusError(err)
+}
+
+// Heartbeat pings the task server to let it know that the runner is still alive
+func (c *Client) Heartbeat(ctx context.Context, r *client.RegisterRequest) (*client.HeartbeatResponse, error) {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.manager.Heartbeat(ctx, ToRegisterRequest(r))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &client.HeartbeatResponse{Resource: client.HeartbeatData{
+		DelegateID:     resp.GetDelegateId(),
+		Status:         resp.GetStatus(),
+		AbortedTaskIDs: resp.GetAbortedTaskIds(),
+	}}, nil
+}
+
+// GetTaskEvents gets the task events which are pending for the delegate
+func (c *Client) GetTaskEvents(ctx context.Context, delegateID string) (*client.TaskEventsResponse, error) {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.manager.GetTaskEvents(ctx, &pb.TaskEventsRequest{DelegateId: delegateID})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	events := &client.TaskEventsResponse{}
+	for _, ev := range resp.GetTaskEvents() {
+		events.TaskEvents = append(events.TaskEvents, FromTaskEvent(ev))
+	}
+	return events, nil
+}
+
+// Acquire tells the task server that the runner is ready to execute a task ID
+func (c *Client) Acquire(ctx context.Context, delegateID, taskID string) (*client.Task, error) {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, err := c.manager.Acquire(ctx, &pb.AcquireRequest{DelegateId: delegateID, TaskId: taskID})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return FromTask(task), nil
+}
+
+// SendStatus sends a response to the task server for a task ID
+func (c *Client) SendStatus(ctx context.Context, delegateID, taskID string, r *client.TaskResponse) error {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.manager.SendStatus(ctx, &pb.SendStatusRequest{DelegateId: delegateID, TaskId: taskID, Response: ToTaskResponse(r)})
+	return statusError(err)
+}
+
+// SendRunnerStatus sends a runner response to the task server for a task ID
+func (c *Client) SendRunnerStatus(ctx context.Context, delegateID, taskID string, r *client.RunnerTaskResponse) error {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.manager.SendRunnerStatus(ctx, &pb.SendRunnerStatusRequest{
+		DelegateId: delegateID,
+		TaskId:     taskID,
+		Response:   ToRunnerTaskResponse(r),
+	})
+	return statusError(err)
+}
+
+// RegisterCapacity registers the capacity of the delegate
+func (c *Client) RegisterCapacity(ctx context.Context, delegateID string, r *client.DelegateCapacity) error {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.manager.RegisterCapacity(ctx, &pb.RegisterCapacityRequest{
+		DelegateId: delegateID,
+		Capacity:   &pb.DelegateCapacity{MaxBuilds: int32(r.MaxBuilds)},
+	})
+	return statusError(err)
+}
+
+// RefreshToken drops the cached account token, so that the next call is made with a new one.
+// It has no effect on a client created with a fixed token.
+func (c *Client) RefreshToken() {
+	if c.AccountTokenCache != nil {
+		c.AccountTokenCache.Invalidate()
+	}
+}
+
+// OpenStream opens the task event stream for the delegate. The returned recv function blocks
+// until the next task event arrives and returns an error once the stream is broken. The stream
+// is closed once ctx is done. It is a poller.OpenFunc:
+//
+//	p.SetSource(poller.NewPushSource(c.OpenStream))
+func (c *Client) OpenStream(ctx context.Context, delegateID string) (func() (*client.TaskEvent, error), error) {
+	ctx, err := c.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.manager.StreamTaskEvents(ctx, &pb.TaskEventsRequest{DelegateId: delegateID})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return func() (*client.TaskEvent, error) {
+		ev, err := stream.Recv()
+		if err != nil {
+			return nil, statusError(err)
+		}
+		return FromTaskEvent(ev), nil
+	}, nil
+}
+
+// authorize adds the delegate token to the outgoing metadata
+func (c *Client) authorize(ctx context.Context) (context.Context, error) {
+	token := c.Token
+	if token == "" {
+		if c.AccountTokenCache == nil {
+			return nil, errors.New("no delegate token configured")
+		}
+		var err error
+		token, err = c.AccountTokenCache.Get()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Delegate "+token), nil
+}
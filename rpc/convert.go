@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"github.com/wings-software/dlite/client"
+	pb "github.com/wings-software/dlite/rpc/delegatepb"
+)
+
+// conversions between the types of the client package and their protocol buffers, which
+// task servers like rpctest share with the client
+
+// ToRegisterRequest converts a register request to its protocol buffer
+func ToRegisterRequest(r *client.RegisterRequest) *pb.RegisterRequest {
+	return &pb.RegisterRequest{
+		AccountId:          r.AccountID,
+		DelegateName:       r.DelegateName,
+		Token:              r.Token,
+		LastHeartbeat:      r.LastHeartbeat,
+		Id:                 r.ID,
+		Type:               r.Type,
+		Ng:                 r.NG,
+		Polling:            r.Polling,
+		HostName:           r.HostName,
+		Connected:          r.Connected,
+		KeepAlivePacket:    r.KeepAlivePacket,
+		SequenceNum:        int32(r.SequenceNum),
+		Ip:                 r.IP,
+		SupportedTaskTypes: r.SupportedTaskTypes,
+		Tags:               r.Tags,
+		HeartbeatAsObject:  r.HeartbeatAsObject,
+	}
+}
+
+// FromRegisterRequest converts a register request from its protocol buffer
+func FromRegisterRequest(r *pb.RegisterRequest) *client.RegisterRequest {
+	return &client.RegisterRequest{
+		AccountID:          r.GetAccountId(),
+		DelegateName:       r.GetDelegateName(),
+		Token:              r.GetToken(),
+		LastHeartbeat:      r.GetLastHeartbeat(),
+		ID:                 r.GetId(),
+		Type:               r.GetType(),
+		NG:                 r.GetNg(),
+		Polling:            r.GetPolling(),
+		HostName:           r.GetHostName(),
+		Connected:          r.GetConnected(),
+		KeepAlivePacket:    r.GetKeepAlivePacket(),
+		SequenceNum:        int(r.GetSequenceNum()),
+		IP:                 r.GetIp(),
+		SupportedTaskTypes: r.GetSupportedTaskTypes(),
+		Tags:               r.GetTags(),
+		HeartbeatAsObject:  r.GetHeartbeatAsObject(),
+	}
+}
+
+// ToTaskEvent converts a task event to its protocol buffer
+func ToTaskEvent(ev *client.TaskEvent) *pb.TaskEvent {
+	return &pb.TaskEvent{AccountId: ev.AccountID, TaskId: ev.TaskID, Sync: ev.Sync, TaskType: ev.TaskType}
+}
+
+// FromTaskEvent converts a task event from its protocol buffer
+func FromTaskEvent(ev *pb.TaskEvent) *client.TaskEvent {
+	return &client.TaskEvent{AccountID: ev.GetAccountId(), TaskID: ev.GetTaskId(), Sync: ev.GetSync(), TaskType: ev.GetTaskType()}
+}
+
+// ToTask converts a task to its protocol buffer
+func ToTask(t *client.Task) *pb.Task {
+	return &pb.Task{
+		Id:             t.ID,
+		Type:           t.Type,
+		Data:           t.Data,
+		Async:          t.Async,
+		RunnerResponse: t.RunnerResponse,
+		Timeout:        int64(t.Timeout),
+		Logging:        &pb.LogInfo{Token: t.Logging.Token, Abstractions: t.Logging.Abstractions},
+		Delegate:       &pb.DelegateInfo{Id: t.DelegateInfo.ID, InstanceId: t.DelegateInfo.InstanceID, Token: t.DelegateInfo.Token},
+		Capabilities:   t.Capabilities,
+	}
+}
+
+// FromTask converts a task from its protocol buffer
+func FromTask(t *pb.Task) *client.Task {
+	return &client.Task{
+		ID:             t.GetId(),
+		Type:           t.GetType(),
+		Data:           t.GetData(),
+		Async:          t.GetAsync(),
+		RunnerResponse: t.GetRunnerResponse(),
+		Timeout:        int(t.GetTimeout()),
+		Logging: client.LogInfo{
+			Token:        t.GetLogging().GetToken(),
+			Abstractions: t.GetLogging().GetAbstractions(),
+		},
+		DelegateInfo: client.DelegateInfo{
+			ID:         t.GetDelegate().GetId(),
+			InstanceID: t.GetDelegate().GetInstanceId(),
+			Token:      t.GetDelegate().GetToken(),
+		},
+		Capabilities: t.GetCapabilities(),
+	}
+}
+
+// ToTaskResponse converts a task response to its protocol buffer
+func ToTaskResponse(r *client.TaskResponse) *pb.TaskResponse {
+	return &pb.TaskResponse{Id: r.ID, Data: r.Data, Type: r.Type, Code: r.Code}
+}
+
+// FromTaskResponse converts a task response from its protocol buffer
+func FromTaskResponse(r *pb.TaskResponse) *client.TaskResponse {
+	return &client.TaskResponse{ID: r.GetId(), Data: r.GetData(), Type: r.GetType(), Code: r.GetCode()}
+}
+
+// ToRunnerTaskResponse converts a runner task response to its protocol buffer
+func ToRunnerTaskResponse(r *client.RunnerTaskResponse) *pb.RunnerTaskResponse {
+	return &pb.RunnerTaskResponse{Id: r.ID, Type: r.Type, Code: string(r.Code), Error: r.Error, Data: r.Data}
+}
+
+// FromRunnerTaskResponse converts a runner task response from its protocol buffer
+func FromRunnerTaskResponse(r *pb.RunnerTaskResponse) *client.RunnerTaskResponse {
+	return &client.RunnerTaskResponse{
+		ID:    r.GetId(),
+		Type:  r.GetType(),
+		Code:  client.ResponseCode(r.GetCode()),
+		Error: r.GetError(),
+		Data:  r.GetData(),
+	}
+}
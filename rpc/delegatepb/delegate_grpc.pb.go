@@ -0,0 +1,456 @@
+// Protocol between delegates and the task server, mirroring the types of the client package.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: delegate.proto
+
+package delegatepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Manager_Register_FullMethodName         = "/dlite.delegate.v1.Manager/Register"
+	Manager_Unregister_FullMethodName       = "/dlite.delegate.v1.Manager/Unregister"
+	Manager_Heartbeat_FullMethodName        = "/dlite.delegate.v1.Manager/Heartbeat"
+	Manager_GetTaskEvents_FullMethodName    = "/dlite.delegate.v1.Manager/GetTaskEvents"
+	Manager_StreamTaskEvents_FullMethodName = "/dlite.delegate.v1.Manager/StreamTaskEvents"
+	Manager_Acquire_FullMethodName          = "/dlite.delegate.v1.Manager/Acquire"
+	Manager_SendStatus_FullMethodName       = "/dlite.delegate.v1.Manager/SendStatus"
+	Manager_SendRunnerStatus_FullMethodName = "/dlite.delegate.v1.Manager/SendRunnerStatus"
+	Manager_RegisterCapacity_FullMethodName = "/dlite.delegate.v1.Manager/RegisterCapacity"
+)
+
+// ManagerClient is the client API for Manager service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ManagerClient interface {
+	// Register registers the delegate with the task server
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
+	// Unregister tells the task server that the delegate is shutting down
+	Unregister(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Heartbeat lets the task server know that the delegate is still alive
+	Heartbeat(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	// GetTaskEvents returns the task events which are pending for the delegate
+	GetTaskEvents(ctx context.Context, in *TaskEventsRequest, opts ...grpc.CallOption) (*TaskEventsResponse, error)
+	// StreamTaskEvents pushes the task events for the delegate as they become pending,
+	// starting with the ones which are already pending.
+	StreamTaskEvents(ctx context.Context, in *TaskEventsRequest, opts ...grpc.CallOption) (Manager_StreamTaskEventsClient, error)
+	// Acquire tells the task server that the delegate is ready to execute a task
+	Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*Task, error)
+	// SendStatus sends the response of a task
+	SendStatus(ctx context.Context, in *SendStatusRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// SendRunnerStatus sends the response of a task which expects a runner response
+	SendRunnerStatus(ctx context.Context, in *SendRunnerStatusRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RegisterCapacity registers the number of tasks the delegate can execute at once
+	RegisterCapacity(ctx context.Context, in *RegisterCapacityRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type managerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewManagerClient(cc grpc.ClientConnInterface) ManagerClient {
+	return &managerClient{cc}
+}
+
+func (c *managerClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error) {
+	out := new(RegisterResponse)
+	err := c.cc.Invoke(ctx, Manager_Register_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) Unregister(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Manager_Unregister_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) Heartbeat(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, Manager_Heartbeat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) GetTaskEvents(ctx context.Context, in *TaskEventsRequest, opts ...grpc.CallOption) (*TaskEventsResponse, error) {
+	out := new(TaskEventsResponse)
+	err := c.cc.Invoke(ctx, Manager_GetTaskEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) StreamTaskEvents(ctx context.Context, in *TaskEventsRequest, opts ...grpc.CallOption) (Manager_StreamTaskEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Manager_ServiceDesc.Streams[0], Manager_StreamTaskEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managerStreamTaskEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Manager_StreamTaskEventsClient interface {
+	Recv() (*TaskEvent, error)
+	grpc.ClientStream
+}
+
+type managerStreamTaskEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managerStreamTaskEventsClient) Recv() (*TaskEvent, error) {
+	m := new(TaskEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managerClient) Acquire(ctx context.Context, in *AcquireRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := c.cc.Invoke(ctx, Manager_Acquire_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) SendStatus(ctx context.Context, in *SendStatusRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Manager_SendStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) SendRunnerStatus(ctx context.Context, in *SendRunnerStatusRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Manager_SendRunnerStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managerClient) RegisterCapacity(ctx context.Context, in *RegisterCapacityRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Manager_RegisterCapacity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ManagerServer is the server API for Manager service.
+// All implementations must embed UnimplementedManagerServer
+// for forward compatibility
+type ManagerServer interface {
+	// Register registers the delegate with the task server
+	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	// Unregister tells the task server that the delegate is shutting down
+	Unregister(context.Context, *RegisterRequest) (*emptypb.Empty, error)
+	// Heartbeat lets the task server know that the delegate is still alive
+	Heartbeat(context.Context, *RegisterRequest) (*HeartbeatResponse, error)
+	// GetTaskEvents returns the task events which are pending for the delegate
+	GetTaskEvents(context.Context, *TaskEventsRequest) (*TaskEventsResponse, error)
+	// StreamTaskEvents pushes the task events for the delegate as they become pending,
+	// starting with the ones which are already pending.
+	StreamTaskEvents(*TaskEventsRequest, Manager_StreamTaskEventsServer) error
+	// Acquire tells the task server that the delegate is ready to execute a task
+	Acquire(context.Context, *AcquireRequest) (*Task, error)
+	// SendStatus sends the response of a task
+	SendStatus(context.Context, *SendStatusRequest) (*emptypb.Empty, error)
+	// SendRunnerStatus sends the response of a task which expects a runner response
+	SendRunnerStatus(context.Context, *SendRunnerStatusRequest) (*emptypb.Empty, error)
+	// RegisterCapacity registers the number of tasks the delegate can execute at once
+	RegisterCapacity(context.Context, *RegisterCapacityRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedManagerServer()
+}
+
+// UnimplementedManagerServer must be embedded to have forward compatible implementations.
+type UnimplementedManagerServer struct {
+}
+
+func (UnimplementedManagerServer) Register(context.Context, *RegisterRequest) (*RegisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedManagerServer) Unregister(context.Context, *RegisterRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unregister not implemented")
+}
+func (UnimplementedManagerServer) Heartbeat(context.Context, *RegisterRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedManagerServer) GetTaskEvents(context.Context, *TaskEventsRequest) (*TaskEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskEvents not implemented")
+}
+func (UnimplementedManagerServer) StreamTaskEvents(*TaskEventsRequest, Manager_StreamTaskEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTaskEvents not implemented")
+}
+func (UnimplementedManagerServer) Acquire(context.Context, *AcquireRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acquire not implemented")
+}
+func (UnimplementedManagerServer) SendStatus(context.Context, *SendStatusRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendStatus not implemented")
+}
+func (UnimplementedManagerServer) SendRunnerStatus(context.Context, *SendRunnerStatusRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendRunnerStatus not implemented")
+}
+func (UnimplementedManagerServer) RegisterCapacity(context.Context, *RegisterCapacityRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterCapacity not implemented")
+}
+func (UnimplementedManagerServer) mustEmbedUnimplementedManagerServer() {}
+
+// UnsafeManagerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ManagerServer will
+// result in compilation errors.
+type UnsafeManagerServer interface {
+	mustEmbedUnimplementedManagerServer()
+}
+
+func RegisterManagerServer(s grpc.ServiceRegistrar, srv ManagerServer) {
+	s.RegisterService(&Manager_ServiceDesc, srv)
+}
+
+func _Manager_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_Unregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).Unregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_Unregister_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).Unregister(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).Heartbeat(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_GetTaskEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).GetTaskEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_GetTaskEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).GetTaskEvents(ctx, req.(*TaskEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_StreamTaskEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TaskEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagerServer).StreamTaskEvents(m, &managerStreamTaskEventsServer{stream})
+}
+
+type Manager_StreamTaskEventsServer interface {
+	Send(*TaskEvent) error
+	grpc.ServerStream
+}
+
+type managerStreamTaskEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managerStreamTaskEventsServer) Send(m *TaskEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Manager_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_Acquire_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).Acquire(ctx, req.(*AcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_SendStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).SendStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_SendStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).SendStatus(ctx, req.(*SendStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_SendRunnerStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRunnerStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).SendRunnerStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_SendRunnerStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).SendRunnerStatus(ctx, req.(*SendRunnerStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Manager_RegisterCapacity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterCapacityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagerServer).RegisterCapacity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Manager_RegisterCapacity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagerServer).RegisterCapacity(ctx, req.(*RegisterCapacityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Manager_ServiceDesc is the grpc.ServiceDesc for Manager service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Manager_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dlite.delegate.v1.Manager",
+	HandlerType: (*ManagerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Manager_Register_Handler,
+		},
+		{
+			MethodName: "Unregister",
+			Handler:    _Manager_Unregister_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Manager_Heartbeat_Handler,
+		},
+		{
+			MethodName: "GetTaskEvents",
+			Handler:    _Manager_GetTaskEvents_Handler,
+		},
+		{
+			MethodName: "Acquire",
+			Handler:    _Manager_Acquire_Handler,
+		},
+		{
+			MethodName: "SendStatus",
+			Handler:    _Manager_SendStatus_Handler,
+		},
+		{
+			MethodName: "SendRunnerStatus",
+			Handler:    _Manager_SendRunnerStatus_Handler,
+		},
+		{
+			MethodName: "RegisterCapacity",
+			Handler:    _Manager_RegisterCapacity_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTaskEvents",
+			Handler:       _Manager_StreamTaskEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "delegate.proto",
+}
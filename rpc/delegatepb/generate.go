@@ -0,0 +1,4 @@
+// Package delegatepb contains the protocol buffers and gRPC service of the delegate protocol.
+package delegatepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative delegate.proto
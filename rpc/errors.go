@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"net/http"
+
+	"github.com/wings-software/dlite/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpStatus maps the gRPC status codes to the HTTP status codes they correspond to
+var httpStatus = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.DataLoss:           http.StatusInternalServerError,
+}
+
+// statusError wraps an error of the task server into a client.StatusError, so that
+// client.IsNotFound and client.IsUnauthorized recognize it. Other errors, like the
+// cancellation of a call, are returned as they are.
+func statusError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	code, ok := httpStatus[s.Code()]
+	if !ok {
+		return err
+	}
+	return &client.StatusError{Code: code, Err: err}
+}
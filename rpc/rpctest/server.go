@@ -0,0 +1,166 @@
+// Package rpctest provides an in-memory reference implementation of the gRPC task server,
+// which can be served in process to run the rpc client and the poller end to end.
+package rpctest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/rpc"
+	pb "github.com/wings-software/dlite/rpc/delegatepb"
+	"github.com/wings-software/dlite/simulator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Server serves the delegates and the task queue of a simulator.Store over gRPC
+type Server struct {
+	pb.UnimplementedManagerServer
+	*simulator.Store
+}
+
+// NewServer returns a reference server with an empty task queue
+func NewServer() *Server {
+	return &Server{Store: simulator.NewStore()}
+}
+
+// GRPCServer returns a gRPC server serving s, which rejects calls without a delegate token
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+			if err := authorized(ctx); err != nil {
+				return nil, err
+			}
+			return h(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+			if err := authorized(ss.Context()); err != nil {
+				return err
+			}
+			return h(srv, ss)
+		}),
+	)
+	gs := grpc.NewServer(opts...)
+	pb.RegisterManagerServer(gs, s)
+	return gs
+}
+
+// Dial serves s in process and returns a connection to it, along with a function which
+// closes the connection and stops the server.
+func (s *Server) Dial(ctx context.Context) (*grpc.ClientConn, func(), error) {
+	lis := bufconn.Listen(1 << 20)
+	gs := s.GRPCServer()
+	go gs.Serve(lis) //nolint:errcheck
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		gs.Stop()
+		return nil, nil, err
+	}
+	return conn, func() {
+		conn.Close()
+		gs.Stop()
+	}, nil
+}
+
+func (s *Server) Register(ctx context.Context, r *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	return &pb.RegisterResponse{DelegateId: s.Store.Register(*rpc.FromRegisterRequest(r))}, nil
+}
+
+func (s *Server) Unregister(ctx context.Context, r *pb.RegisterRequest) (*emptypb.Empty, error) {
+	if err := s.Store.Unregister(r.GetId()); err != nil {
+		return nil, statusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Heartbeat(ctx context.Context, r *pb.RegisterRequest) (*pb.HeartbeatResponse, error) {
+	data := s.Store.Heartbeat(r.GetId())
+	return &pb.HeartbeatResponse{DelegateId: data.DelegateID, Status: data.Status, AbortedTaskIds: data.AbortedTaskIDs}, nil
+}
+
+func (s *Server) GetTaskEvents(ctx context.Context, r *pb.TaskEventsRequest) (*pb.TaskEventsResponse, error) {
+	evs, err := s.Pending(r.GetDelegateId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &pb.TaskEventsResponse{}
+	for _, ev := range evs {
+		resp.TaskEvents = append(resp.TaskEvents, rpc.ToTaskEvent(ev))
+	}
+	return resp, nil
+}
+
+// StreamTaskEvents pushes the task events of the delegate as they become pending.
+// Every event is sent once per stream.
+func (s *Server) StreamTaskEvents(r *pb.TaskEventsRequest, stream pb.Manager_StreamTaskEventsServer) error {
+	err := s.Push(stream.Context(), r.GetDelegateId(), func(ev *client.TaskEvent) error {
+		return stream.Send(rpc.ToTaskEvent(ev))
+	})
+	return statusError(err)
+}
+
+func (s *Server) Acquire(ctx context.Context, r *pb.AcquireRequest) (*pb.Task, error) {
+	task, err := s.Store.Acquire(r.GetDelegateId(), r.GetTaskId(), "")
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return rpc.ToTask(task), nil
+}
+
+func (s *Server) SendStatus(ctx context.Context, r *pb.SendStatusRequest) (*emptypb.Empty, error) {
+	if err := s.SetResponse(r.GetTaskId(), rpc.FromTaskResponse(r.GetResponse())); err != nil {
+		return nil, statusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) SendRunnerStatus(ctx context.Context, r *pb.SendRunnerStatusRequest) (*emptypb.Empty, error) {
+	if err := s.SetRunnerResponse(r.GetTaskId(), rpc.FromRunnerTaskResponse(r.GetResponse())); err != nil {
+		return nil, statusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) RegisterCapacity(ctx context.Context, r *pb.RegisterCapacityRequest) (*emptypb.Empty, error) {
+	capacity := client.DelegateCapacity{MaxBuilds: int(r.GetCapacity().GetMaxBuilds())}
+	if err := s.Store.RegisterCapacity(r.GetDelegateId(), capacity); err != nil {
+		return nil, statusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// statusError maps the errors of the store onto gRPC status codes
+func statusError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, simulator.ErrAcquired):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, simulator.ErrDelegateNotFound), errors.Is(err, simulator.ErrTaskNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return err
+	}
+}
+
+// authorized rejects calls which do not carry a delegate token. The token itself is not verified.
+func authorized(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if strings.HasPrefix(v, "Delegate ") {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing delegate token")
+}
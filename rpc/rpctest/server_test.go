@@ -0,0 +1,117 @@
+package rpctest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/delegate"
+	"github.com/wings-software/dlite/poller"
+	"github.com/wings-software/dlite/router"
+	"github.com/wings-software/dlite/rpc"
+	pb "github.com/wings-software/dlite/rpc/delegatepb"
+	"github.com/wings-software/dlite/rpc/rpctest"
+	"github.com/wings-software/dlite/task"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testTaskType = "TEST_TASK"
+
+var _ rpc.TokenSource = (*delegate.TokenCache)(nil)
+
+// dial serves s in process and returns a connection to it
+func dial(t *testing.T, s *rpctest.Server) *grpc.ClientConn {
+	t.Helper()
+	conn, stop, err := s.Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stop)
+	return conn
+}
+
+func TestPollerWithServer(t *testing.T) {
+	s := rpctest.NewServer()
+	c := rpc.NewFromToken(dial(t, s), "token")
+	r := router.NewRouter(map[string]task.Handler{testTaskType: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})})
+	p := poller.New("account", "secret", "runner", nil, c, r)
+	p.SetSource(poller.NewPushSource(c.OpenStream))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	info, err := p.Register(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := s.Enqueue(
+		client.Task{Type: testTaskType, RunnerResponse: true},
+		client.Task{Type: testTaskType, RunnerResponse: true},
+		client.Task{Type: testTaskType, RunnerResponse: true},
+	)
+	done := make(chan error, 1)
+	go func() { done <- p.Poll(ctx, len(ids), info.ID, 10*time.Millisecond) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		completed := 0
+		for _, task := range s.Tasks() {
+			if task.RunnerResponse != nil && task.RunnerResponse.Code == client.Success {
+				completed++
+			}
+		}
+		if completed == len(ids) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d completed tasks, want %d", completed, len(ids))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("poll failed: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for poll to return")
+	}
+	if d := s.Delegates()[info.ID]; !d.Unregistered {
+		t.Error("delegate did not unregister")
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	s := rpctest.NewServer()
+	conn := dial(t, s)
+	c := rpc.NewFromToken(conn, "token")
+	ctx := context.Background()
+
+	if _, err := pb.NewManagerClient(conn).Register(ctx, &pb.RegisterRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("got error %v without a delegate token, want %s", err, codes.Unauthenticated)
+	}
+	if _, err := c.Acquire(ctx, "unknown", "task"); !client.IsNotFound(err) {
+		t.Errorf("got error %v for an unknown delegate, want a not found error", err)
+	}
+	resp, err := c.Register(ctx, &client.RegisterRequest{SupportedTaskTypes: []string{testTaskType}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := resp.Resource.DelegateID
+	if _, err := c.Acquire(ctx, id, "unknown"); !client.IsNotFound(err) {
+		t.Errorf("got error %v for an unknown task, want a not found error", err)
+	}
+	taskID := s.Enqueue(client.Task{Type: testTaskType})[0]
+	if _, err := c.Acquire(ctx, id, taskID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Acquire(ctx, id, taskID); err == nil {
+		t.Error("acquired a task twice")
+	} else if code, _ := client.StatusCode(err); code != http.StatusConflict {
+		t.Errorf("got status %d for a task acquired twice, want %d", code, http.StatusConflict)
+	}
+}
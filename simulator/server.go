@@ -0,0 +1,382 @@
+// Package simulator provides a local stand-in for the Harness manager. It implements the
+// delegate API used by delegate.HTTPClient on top of an in-memory task queue, along with
+// an admin API to enqueue tasks and inspect what the delegates reported. Besides polling,
+// task events can be long polled or streamed as server-sent events, to try out the
+// poller.LongPollSource and poller.StreamSource. The delegates and tasks are kept in a Store,
+// which the reference servers of other transports, like rpctest, are built on.
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/httphelper"
+)
+
+type (
+	// Delegate is a delegate registered with the simulator
+	Delegate struct {
+		Request       client.RegisterRequest   `json:"request"`
+		Capacity      *client.DelegateCapacity `json:"capacity,omitempty"`
+		LastHeartbeat time.Time                `json:"last_heartbeat"`
+		Deleted       bool                     `json:"deleted"`
+		// Unregistered is set when the delegate unregistered itself, which also deletes it
+		Unregistered bool `json:"unregistered"`
+	}
+
+	// Task is a task queued in the simulator along with what happened to it
+	Task struct {
+		Task           client.Task                `json:"task"`
+		AcquiredBy     []string                   `json:"acquired_by,omitempty"`
+		Response       *client.TaskResponse       `json:"response,omitempty"`
+		RunnerResponse *client.RunnerTaskResponse `json:"runner_response,omitempty"`
+	}
+)
+
+// Server simulates the delegate API of the Harness manager
+type Server struct {
+	*Store
+
+	mux *http.ServeMux
+}
+
+var (
+	// Time a long poll request is held open for when it does not ask for a wait time
+	longPollWait = 30 * time.Second
+	// Longest time a long poll request is held open for
+	maxLongPollWait = 5 * time.Minute
+)
+
+// New returns a simulator with an empty task queue
+func New() *Server {
+	s := &Server{Store: NewStore(), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/api/agent/delegates/register", s.authorized(s.handleRegister))
+	s.mux.HandleFunc("/api/agent/delegates/unregister", s.authorized(s.handleUnregister))
+	s.mux.HandleFunc("/api/agent/delegates/heartbeat-with-polling", s.authorized(s.handleHeartbeat))
+	s.mux.HandleFunc("/api/agent/delegates/register-delegate-capacity/", s.authorized(s.handleCapacity))
+	s.mux.HandleFunc("/api/agent/delegates/", s.authorized(s.handleTaskEvents))
+	s.mux.HandleFunc("/api/agent/v2/delegates/", s.authorized(s.handleAcquire))
+	s.mux.HandleFunc("/api/agent/v2/tasks/", s.authorized(s.handleStatus))
+	s.mux.HandleFunc("/api/executions/", s.authorized(s.handleExecutionResponse))
+	s.mux.HandleFunc("/admin/tasks", s.handleAdminTasks)
+	s.mux.HandleFunc("/admin/tasks/", s.handleAdminTask)
+	s.mux.HandleFunc("/admin/delegates", s.handleAdminDelegates)
+	s.mux.HandleFunc("/admin/delegates/", s.handleAdminDelegate)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logrus.WithField("method", r.Method).WithField("path", r.URL.Path).Debugln("simulator: handling request")
+	s.mux.ServeHTTP(w, r)
+}
+
+// POST /api/agent/delegates/register
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	req := &client.RegisterRequest{}
+	if !decode(w, r, http.MethodPost, req) {
+		return
+	}
+	id := s.Register(*req)
+	httphelper.WriteJSON(w, &client.RegisterResponse{Resource: client.RegistrationData{DelegateID: id}}, http.StatusOK)
+}
+
+// POST /api/agent/delegates/unregister
+func (s *Server) handleUnregister(w http.ResponseWriter, r *http.Request) {
+	req := &client.RegisterRequest{}
+	if !decode(w, r, http.MethodPost, req) {
+		return
+	}
+	if err := s.Unregister(req.ID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// POST /api/agent/delegates/heartbeat-with-polling
+func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	req := &client.RegisterRequest{}
+	if !decode(w, r, http.MethodPost, req) {
+		return
+	}
+	httphelper.WriteJSON(w, &client.HeartbeatResponse{Resource: s.Heartbeat(req.ID)}, http.StatusOK)
+}
+
+// POST /api/agent/delegates/register-delegate-capacity/{delegateId}
+func (s *Server) handleCapacity(w http.ResponseWriter, r *http.Request) {
+	req := &client.DelegateCapacity{}
+	if !decode(w, r, http.MethodPost, req) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/agent/delegates/register-delegate-capacity/")
+	if err := s.RegisterCapacity(id, *req); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GET /api/agent/delegates/{delegateId}/task-events,
+// GET /api/agent/delegates/{delegateId}/task-events/long-poll and
+// GET /api/agent/delegates/{delegateId}/task-events/stream
+func (s *Server) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
+	parts := split(r.URL.Path, "/api/agent/delegates/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "task-events" || r.Method != http.MethodGet {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	mode := ""
+	if len(parts) == 3 {
+		mode = parts[2]
+	}
+	switch mode {
+	case "":
+		evs, err := s.Pending(parts[0])
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		httphelper.WriteJSON(w, &client.TaskEventsResponse{TaskEvents: evs}, http.StatusOK)
+	case "long-poll":
+		s.longPoll(w, r, parts[0])
+	case "stream":
+		s.stream(w, r, parts[0])
+	default:
+		httphelper.WriteNotFound(w, errors.New("not found"))
+	}
+}
+
+// longPoll holds the request open until task events are pending for the delegate or the wait
+// time is over, in which case it responds with 204 No Content. The wait time can be set with
+// the wait query parameter, in seconds.
+func (s *Server) longPoll(w http.ResponseWriter, r *http.Request, delegateID string) {
+	wait := longPollWait
+	if v := r.URL.Query().Get("wait"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			httphelper.WriteBadRequest(w, fmt.Errorf("invalid wait time: %s", v))
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait > maxLongPollWait {
+		wait = maxLongPollWait
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	for {
+		changed := s.Changes()
+		evs, err := s.Pending(delegateID)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if len(evs) > 0 {
+			httphelper.WriteJSON(w, &client.TaskEventsResponse{TaskEvents: evs}, http.StatusOK)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-changed:
+		}
+	}
+}
+
+// stream pushes the task events of the delegate as server-sent events as they become pending.
+// Every event is sent once per stream.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, delegateID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.New("streaming is not supported"), http.StatusInternalServerError)
+		return
+	}
+	if _, err := s.Pending(delegateID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	err := s.Push(r.Context(), delegateID, func(ev *client.TaskEvent) error {
+		data, _ := json.Marshal(ev)
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("id", delegateID).Debugln("simulator: task event stream closed")
+	}
+}
+
+// PUT /api/agent/v2/delegates/{delegateId}/tasks/{taskId}/acquire
+func (s *Server) handleAcquire(w http.ResponseWriter, r *http.Request) {
+	parts := split(r.URL.Path, "/api/agent/v2/delegates/")
+	if len(parts) != 4 || parts[1] != "tasks" || parts[3] != "acquire" || r.Method != http.MethodPut {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	task, err := s.Acquire(parts[0], parts[2], r.URL.Query().Get("delegateInstanceId"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	httphelper.WriteJSON(w, task, http.StatusOK)
+}
+
+// POST /api/agent/v2/tasks/{taskId}/delegates/{delegateId}
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	parts := split(r.URL.Path, "/api/agent/v2/tasks/")
+	if len(parts) != 3 || parts[1] != "delegates" {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	req := &client.TaskResponse{}
+	if !decode(w, r, http.MethodPost, req) {
+		return
+	}
+	if err := s.SetResponse(parts[0], req); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// POST /api/executions/{taskId}/response and POST /api/executions/{taskId}/task-response
+func (s *Server) handleExecutionResponse(w http.ResponseWriter, r *http.Request) {
+	parts := split(r.URL.Path, "/api/executions/")
+	if len(parts) != 2 || (parts[1] != "response" && parts[1] != "task-response") {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	req := &client.RunnerTaskResponse{}
+	if !decode(w, r, http.MethodPost, req) {
+		return
+	}
+	if err := s.SetRunnerResponse(parts[0], req); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GET lists the tasks, POST enqueues a task or a list of tasks
+func (s *Server) handleAdminTasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		httphelper.WriteJSON(w, s.Tasks(), http.StatusOK)
+	case http.MethodPost:
+		var raw json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			httphelper.WriteBadRequest(w, err)
+			return
+		}
+		var tasks []client.Task
+		if err := json.Unmarshal(raw, &tasks); err != nil {
+			task := client.Task{}
+			if err := json.Unmarshal(raw, &task); err != nil {
+				httphelper.WriteBadRequest(w, err)
+				return
+			}
+			tasks = append(tasks, task)
+		}
+		httphelper.WriteJSON(w, s.Enqueue(tasks...), http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// POST /admin/tasks/{taskId}/abort
+func (s *Server) handleAdminTask(w http.ResponseWriter, r *http.Request) {
+	parts := split(r.URL.Path, "/admin/tasks/")
+	if len(parts) != 2 || parts[1] != "abort" || r.Method != http.MethodPost {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	if err := s.Abort(parts[0]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET lists the registered delegates
+func (s *Server) handleAdminDelegates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	httphelper.WriteJSON(w, s.Delegates(), http.StatusOK)
+}
+
+// DELETE /admin/delegates/{delegateId}
+func (s *Server) handleAdminDelegate(w http.ResponseWriter, r *http.Request) {
+	parts := split(r.URL.Path, "/admin/delegates/")
+	if len(parts) != 1 || r.Method != http.MethodDelete {
+		httphelper.WriteNotFound(w, errors.New("not found"))
+		return
+	}
+	if err := s.Forget(parts[0]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized rejects requests which do not carry a delegate token. The token itself is not verified.
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Delegate ") {
+			writeError(w, errors.New("missing delegate token"), http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// decode checks the request method and decodes the json request body into v
+func decode(w http.ResponseWriter, r *http.Request, method string, v interface{}) bool {
+	if r.Method != method {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		httphelper.WriteBadRequest(w, err)
+		return false
+	}
+	return true
+}
+
+// split returns the segments of the path after the prefix
+func split(path, prefix string) []string {
+	return strings.Split(strings.Trim(strings.TrimPrefix(path, prefix), "/"), "/")
+}
+
+func writeError(w http.ResponseWriter, err error, status int) {
+	httphelper.WriteJSON(w, struct {
+		Message string `json:"error_msg"`
+		Status  int    `json:"code"`
+	}{err.Error(), status}, status)
+}
+
+// writeStoreError responds with the status code matching an error of the store
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrAcquired) {
+		writeError(w, err, http.StatusConflict)
+		return
+	}
+	httphelper.WriteNotFound(w, err)
+}
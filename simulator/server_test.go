@@ -0,0 +1,200 @@
+package simulator_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/wings-software/dlite/client"
+	"github.com/wings-software/dlite/delegate"
+	"github.com/wings-software/dlite/simulator"
+)
+
+const testTaskType = "TEST_TASK"
+
+// start serves a simulator and returns it along with a delegate client registered with it
+func start(t *testing.T) (*simulator.Server, *httptest.Server, *delegate.HTTPClient, string) {
+	t.Helper()
+	s := simulator.New()
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	c := delegate.NewFromToken(server.URL, "account", "token", false, "")
+	resp, err := c.Register(context.Background(), &client.RegisterRequest{SupportedTaskTypes: []string{testTaskType}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, server, c, resp.Resource.DelegateID
+}
+
+// admin sends a request to the admin API and decodes the response into out, if it is not nil
+func admin(t *testing.T, method, url string, body interface{}, wantStatus int, out interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != wantStatus {
+		t.Fatalf("got status %d for %s %s, want %d", res.StatusCode, method, url, wantStatus)
+	}
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAcquireTwice(t *testing.T) {
+	s, _, c, id := start(t)
+	ctx := context.Background()
+	taskID := s.Enqueue(client.Task{Type: testTaskType})[0]
+	task, err := c.Acquire(ctx, id, taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ID != taskID || task.DelegateInfo.ID != id {
+		t.Errorf("got task %s acquired by %s, want %s acquired by %s", task.ID, task.DelegateInfo.ID, taskID, id)
+	}
+	if _, err := c.Acquire(ctx, id, taskID); err == nil {
+		t.Error("acquired a task twice")
+	} else if code, _ := client.StatusCode(err); code != http.StatusConflict {
+		t.Errorf("got status %d for a task acquired twice, want %d", code, http.StatusConflict)
+	}
+	if _, err := c.Acquire(ctx, id, "unknown"); !client.IsNotFound(err) {
+		t.Errorf("got error %v for an unknown task, want a not found error", err)
+	}
+
+	// the Harness manager lets a delegate acquire a task again, but not another delegate
+	s.AllowDoubleAcquire = true
+	if _, err := c.Acquire(ctx, id, taskID); err != nil {
+		t.Errorf("could not acquire a task again with AllowDoubleAcquire: %s", err)
+	}
+	other, err := c.Register(ctx, &client.RegisterRequest{SupportedTaskTypes: []string{testTaskType}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Acquire(ctx, other.Resource.DelegateID, taskID); err == nil {
+		t.Error("another delegate acquired the task with AllowDoubleAcquire")
+	}
+}
+
+func TestAbortInHeartbeat(t *testing.T) {
+	s, server, c, id := start(t)
+	ctx := context.Background()
+	taskID := s.Enqueue(client.Task{Type: testTaskType})[0]
+	if _, err := c.Acquire(ctx, id, taskID); err != nil {
+		t.Fatal(err)
+	}
+	admin(t, http.MethodPost, server.URL+"/admin/tasks/"+taskID+"/abort", nil, http.StatusNoContent, nil)
+
+	resp, err := c.Heartbeat(ctx, &client.RegisterRequest{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Resource.AbortedTaskIDs; !reflect.DeepEqual(got, []string{taskID}) {
+		t.Errorf("got aborted tasks %v, want %s", got, taskID)
+	}
+	resp, err = c.Heartbeat(ctx, &client.RegisterRequest{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Resource.AbortedTaskIDs; len(got) != 0 {
+		t.Errorf("got aborted tasks %v in the next heartbeat, want none", got)
+	}
+	admin(t, http.MethodPost, server.URL+"/admin/tasks/unknown/abort", nil, http.StatusNotFound, nil)
+}
+
+func TestForgetDelegate(t *testing.T) {
+	_, server, c, id := start(t)
+	ctx := context.Background()
+	admin(t, http.MethodDelete, server.URL+"/admin/delegates/"+id, nil, http.StatusNoContent, nil)
+
+	resp, err := c.Heartbeat(ctx, &client.RegisterRequest{ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Resource.Status != client.DelegateDeleted {
+		t.Errorf("got status %q in the heartbeat of a forgotten delegate, want %q", resp.Resource.Status, client.DelegateDeleted)
+	}
+	if _, err := c.GetTaskEvents(ctx, id); !client.IsNotFound(err) {
+		t.Errorf("got error %v for the task events of a forgotten delegate, want a not found error", err)
+	}
+	var delegates map[string]simulator.Delegate
+	admin(t, http.MethodGet, server.URL+"/admin/delegates", nil, http.StatusOK, &delegates)
+	if d, ok := delegates[id]; !ok || !d.Deleted || d.Unregistered {
+		t.Errorf("got delegate %+v, want it deleted without having unregistered", d)
+	}
+	admin(t, http.MethodDelete, server.URL+"/admin/delegates/unknown", nil, http.StatusNotFound, nil)
+}
+
+func TestAdminTasks(t *testing.T) {
+	_, server, c, id := start(t)
+	ctx := context.Background()
+	var ids []string
+	admin(t, http.MethodPost, server.URL+"/admin/tasks", client.Task{ID: "task-1", Type: testTaskType, RunnerResponse: true}, http.StatusCreated, &ids)
+	if !reflect.DeepEqual(ids, []string{"task-1"}) {
+		t.Errorf("got IDs %v for a single task, want task-1", ids)
+	}
+	admin(t, http.MethodPost, server.URL+"/admin/tasks", []client.Task{{Type: testTaskType}, {Type: "OTHER"}}, http.StatusCreated, &ids)
+	if len(ids) != 2 || ids[0] == "" || ids[1] == "" {
+		t.Errorf("got IDs %v for a list of tasks, want two generated IDs", ids)
+	}
+	admin(t, http.MethodPost, server.URL+"/admin/tasks", "not a task", http.StatusBadRequest, nil)
+	admin(t, http.MethodPut, server.URL+"/admin/tasks", nil, http.StatusMethodNotAllowed, nil)
+
+	resp, err := c.GetTaskEvents(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(resp.TaskEvents); n != 2 {
+		t.Errorf("got %d task events, want the 2 tasks of the supported type", n)
+	}
+	if _, err := c.Acquire(ctx, id, "task-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SendRunnerStatus(ctx, id, "task-1", &client.RunnerTaskResponse{ID: "task-1", Code: client.Success}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tasks []simulator.Task
+	admin(t, http.MethodGet, server.URL+"/admin/tasks", nil, http.StatusOK, &tasks)
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks, want 3", len(tasks))
+	}
+	if got := []string{tasks[0].Task.ID, tasks[1].Task.ID, tasks[2].Task.ID}; !reflect.DeepEqual(got, append([]string{"task-1"}, ids...)) {
+		t.Errorf("got tasks %v, want them in the order they were enqueued", got)
+	}
+	first := tasks[0]
+	if !reflect.DeepEqual(first.AcquiredBy, []string{id}) {
+		t.Errorf("got task acquired by %v, want %s", first.AcquiredBy, id)
+	}
+	if first.RunnerResponse == nil || first.RunnerResponse.Code != client.Success {
+		t.Errorf("got runner response %+v, want %s", first.RunnerResponse, client.Success)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	_, server, _, id := start(t)
+	res, err := http.Get(server.URL + "/api/agent/delegates/" + id + "/task-events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d without a delegate token, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,304 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/wings-software/dlite/client"
+	"k8s.io/utils/strings/slices"
+)
+
+var (
+	// ErrDelegateNotFound is returned for delegates which are not registered
+	ErrDelegateNotFound = errors.New("delegate not found")
+	// ErrTaskNotFound is returned for tasks which are not queued
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrAcquired is returned when acquiring a task which has already been acquired
+	ErrAcquired = errors.New("task has already been acquired")
+)
+
+// Store holds the registered delegates and the task queue of a simulated task server.
+// It is independent of the transport, so that the simulator and the reference servers
+// of other protocols, like rpctest, behave the same.
+type Store struct {
+	// AllowDoubleAcquire lets a delegate acquire a task it has already acquired,
+	// as the Harness manager does.
+	AllowDoubleAcquire bool
+
+	mu        sync.Mutex
+	delegates map[string]*Delegate
+	tasks     map[string]*Task
+	order     []string // task IDs in the order they were enqueued
+	aborted   map[string][]string
+	changed   chan struct{} // closed and replaced whenever the pending task events might have changed
+}
+
+// NewStore returns a store with an empty task queue
+func NewStore() *Store {
+	return &Store{
+		delegates: map[string]*Delegate{},
+		tasks:     map[string]*Task{},
+		aborted:   map[string][]string{},
+		changed:   make(chan struct{}),
+	}
+}
+
+// Enqueue adds tasks to the queue. Tasks without an ID get a generated one.
+// It returns the IDs of the tasks.
+func (s *Store) Enqueue(tasks ...client.Task) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for i := range tasks {
+		task := tasks[i]
+		if task.ID == "" {
+			task.ID = uuid.New().String()
+		}
+		if _, ok := s.tasks[task.ID]; !ok {
+			s.order = append(s.order, task.ID)
+		}
+		s.tasks[task.ID] = &Task{Task: task}
+		ids = append(ids, task.ID)
+	}
+	s.notify()
+	return ids
+}
+
+// Abort aborts a task. The delegates which acquired it find it in their next heartbeat response.
+func (s *Store) Abort(taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.task(taskID)
+	if err != nil {
+		return err
+	}
+	for _, id := range t.AcquiredBy {
+		s.aborted[id] = append(s.aborted[id], taskID)
+	}
+	return nil
+}
+
+// Forget deletes a delegate, so that its heartbeats report it as not registered
+func (s *Store) Forget(delegateID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.delegates[delegateID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDelegateNotFound, delegateID)
+	}
+	d.Deleted = true
+	s.notify()
+	return nil
+}
+
+// Tasks returns a snapshot of all the tasks in the order they were enqueued
+func (s *Store) Tasks() []Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tasks []Task
+	for _, id := range s.order {
+		tasks = append(tasks, *s.tasks[id])
+	}
+	return tasks
+}
+
+// Delegates returns a snapshot of the registered delegates
+func (s *Store) Delegates() map[string]Delegate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delegates := map[string]Delegate{}
+	for id, d := range s.delegates {
+		delegates[id] = *d
+	}
+	return delegates
+}
+
+// Register registers a delegate and returns its generated ID
+func (s *Store) Register(req client.RegisterRequest) string {
+	req.ID = uuid.New().String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delegates[req.ID] = &Delegate{Request: req, LastHeartbeat: time.Now()}
+	s.notify()
+	logrus.WithField("id", req.ID).WithField("host", req.HostName).Infoln("simulator: registered delegate")
+	return req.ID
+}
+
+// Unregister deletes a delegate which is shutting down
+func (s *Store) Unregister(delegateID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.delegates[delegateID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDelegateNotFound, delegateID)
+	}
+	d.Deleted = true
+	d.Unregistered = true
+	s.notify()
+	logrus.WithField("id", delegateID).Infoln("simulator: unregistered delegate")
+	return nil
+}
+
+// Heartbeat records a heartbeat of a delegate. It returns the tasks aborted since the last
+// heartbeat, or the DelegateDeleted status if the delegate is not registered.
+func (s *Store) Heartbeat(delegateID string) client.HeartbeatData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := client.HeartbeatData{DelegateID: delegateID}
+	d, ok := s.delegates[delegateID]
+	switch {
+	case !ok || d.Deleted:
+		data.Status = client.DelegateDeleted
+	default:
+		d.LastHeartbeat = time.Now()
+		data.AbortedTaskIDs = s.aborted[delegateID]
+		delete(s.aborted, delegateID)
+	}
+	return data
+}
+
+// RegisterCapacity records the capacity of a delegate
+func (s *Store) RegisterCapacity(delegateID string, capacity client.DelegateCapacity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.delegates[delegateID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDelegateNotFound, delegateID)
+	}
+	d.Capacity = &capacity
+	return nil
+}
+
+// Pending returns the events of the tasks which have not been acquired and which the
+// delegate supports, in the order the tasks were enqueued
+func (s *Store) Pending(delegateID string) ([]*client.TaskEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.delegate(delegateID)
+	if err != nil {
+		return nil, err
+	}
+	evs := []*client.TaskEvent{}
+	for _, id := range s.order {
+		t := s.tasks[id]
+		if len(t.AcquiredBy) > 0 || !slices.Contains(d.Request.SupportedTaskTypes, t.Task.Type) {
+			continue
+		}
+		evs = append(evs, &client.TaskEvent{
+			AccountID: d.Request.AccountID,
+			TaskID:    t.Task.ID,
+			TaskType:  t.Task.Type,
+			Sync:      !t.Task.Async,
+		})
+	}
+	return evs, nil
+}
+
+// Changes returns a channel which is closed once the pending task events might have changed
+func (s *Store) Changes() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// Push calls send with the task events of the delegate as they become pending, until ctx is
+// done, the delegate is deleted or send fails. Every event is sent once.
+func (s *Store) Push(ctx context.Context, delegateID string, send func(*client.TaskEvent) error) error {
+	sent := map[string]bool{}
+	for {
+		changed := s.Changes()
+		evs, err := s.Pending(delegateID)
+		if err != nil {
+			return err
+		}
+		for _, ev := range evs {
+			if sent[ev.TaskID] {
+				continue
+			}
+			if err := send(ev); err != nil {
+				return err
+			}
+			sent[ev.TaskID] = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+// Acquire hands a task over to a delegate
+func (s *Store) Acquire(delegateID, taskID, instanceID string) (*client.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.delegate(delegateID); err != nil {
+		return nil, err
+	}
+	t, err := s.task(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.AcquiredBy) > 0 && !(s.AllowDoubleAcquire && slices.Contains(t.AcquiredBy, delegateID)) {
+		return nil, fmt.Errorf("%w: %s", ErrAcquired, taskID)
+	}
+	t.AcquiredBy = append(t.AcquiredBy, delegateID)
+	s.notify()
+	logrus.WithField("task_id", taskID).WithField("id", delegateID).Infoln("simulator: task acquired")
+	task := t.Task
+	task.DelegateInfo.ID = delegateID
+	task.DelegateInfo.InstanceID = instanceID
+	return &task, nil
+}
+
+// SetResponse records the response sent for a task
+func (s *Store) SetResponse(taskID string, r *client.TaskResponse) error {
+	return s.record(taskID, func(t *Task) { t.Response = r })
+}
+
+// SetRunnerResponse records the runner response sent for a task
+func (s *Store) SetRunnerResponse(taskID string, r *client.RunnerTaskResponse) error {
+	return s.record(taskID, func(t *Task) { t.RunnerResponse = r })
+}
+
+// record stores the response sent for a task
+func (s *Store) record(taskID string, update func(*Task)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.task(taskID)
+	if err != nil {
+		return err
+	}
+	update(t)
+	logrus.WithField("task_id", taskID).Infoln("simulator: received task response")
+	return nil
+}
+
+// delegate returns a registered delegate. It must be called with the lock held.
+func (s *Store) delegate(id string) (*Delegate, error) {
+	d, ok := s.delegates[id]
+	if !ok || d.Deleted {
+		return nil, fmt.Errorf("%w: %s", ErrDelegateNotFound, id)
+	}
+	return d, nil
+}
+
+// task returns a queued task. It must be called with the lock held.
+func (s *Store) task(id string) (*Task, error) {
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	return t, nil
+}
+
+// notify wakes up the callers waiting for task events. It must be called with the lock held.
+func (s *Store) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
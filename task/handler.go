@@ -4,6 +4,16 @@ import (
 	"net/http"
 )
 
+// ResponseCodeHeader is the response header a handler can set to report the outcome of
+// a task explicitly, using one of the client.ResponseCode values. Without it, the outcome
+// is derived from the status code written by the handler:
+//
+//	2xx       OK
+//	408, 504  TIMEOUT
+//	503       RETRY_ON_OTHER_DELEGATE
+//	others    FAILED
+const ResponseCodeHeader = "X-Task-Response-Code"
+
 // Task Handlers should implement the HTTP handler interface
 // This interface can be extended later to support other communication mechanisms
 type Handler interface {